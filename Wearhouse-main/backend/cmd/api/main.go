@@ -5,6 +5,7 @@ import (
 	"wearhouse/configs"
 	"wearhouse/internal/database"
 	"wearhouse/internal/handlers"
+	"wearhouse/internal/jobs"
 	"wearhouse/internal/middleware"
 	"wearhouse/internal/routes"
 
@@ -30,24 +31,35 @@ func main() {
 
 	// Add CORS middleware
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Idempotency-Key",
+		AllowMethods:  "GET, POST, PUT, DELETE",
+		ExposeHeaders: "Idempotent-Replayed",
 	}))
 
 	// Initialize auth middleware
 	middleware.InitAuth(config.JWTSecret)
 
 	// Initialize payment handler
-	paymentHandler := handlers.NewPaymentHandler(config.StripeSecretKey)
+	paymentHandler := handlers.NewPaymentHandler(config)
 
 	// Setup routes
 	routes.SetupAuthRoutes(app, config)
 	routes.SetupUserRoutes(app, config)
 	routes.SetupProductRoutes(app, config)
 	routes.SetupCartRoutes(app, config)
-	routes.SetupOrderRoutes(app, config)
-	routes.SetupPaymentRoutes(app, paymentHandler)
+	routes.SetupAddressRoutes(app, config)
+	routes.SetupMeetupRoutes(app, config)
+	routes.SetupOrderRoutes(app, config, paymentHandler)
+	routes.SetupOfferRoutes(app, config)
+	routes.SetupSalesRoutes(app, config, paymentHandler)
+	routes.SetupPaymentRoutes(app, config, paymentHandler)
+	routes.SetupFeeRoutes(app, config)
+	routes.SetupTaxRoutes(app, config)
+	routes.SetupCouponRoutes(app, config)
+
+	// Start background jobs
+	jobs.Start(config, paymentHandler)
 
 	// Start server
 	log.Printf("Server starting on port %s", config.Port)
@@ -5,6 +5,7 @@ import (
 	"wearhouse/configs"
 	"wearhouse/internal/database"
 	"wearhouse/internal/handlers"
+	"wearhouse/internal/jobs"
 	"wearhouse/internal/middleware"
 	"wearhouse/internal/routes"
 	"wearhouse/internal/utils"
@@ -55,9 +56,10 @@ func main() {
 	// Middleware
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, Idempotency-Key",
+		AllowMethods:  "GET, POST, PUT, DELETE",
+		ExposeHeaders: "Idempotent-Replayed",
 	}))
 
 	// Initialize auth middleware
@@ -68,16 +70,27 @@ func main() {
 		return c.SendString("OK")
 	})
 
+	// Initialize payment handler, orders refund through it
+	paymentHandler := handlers.NewPaymentHandler(config)
+
 	// Setup routes
 	log.Println("Setting up routes...")
 	routes.SetupAuthRoutes(app, config)
+	routes.SetupUserRoutes(app, config)
 	routes.SetupProductRoutes(app, config)
-	routes.SetupOrderRoutes(app, config)
+	routes.SetupOrderRoutes(app, config, paymentHandler)
 	routes.SetupCartRoutes(app, config)
+	routes.SetupAddressRoutes(app, config)
+	routes.SetupMeetupRoutes(app, config)
+	routes.SetupOfferRoutes(app, config)
+	routes.SetupSalesRoutes(app, config, paymentHandler)
+	routes.SetupPaymentRoutes(app, config, paymentHandler)
+	routes.SetupFeeRoutes(app, config)
+	routes.SetupTaxRoutes(app, config)
+	routes.SetupCouponRoutes(app, config)
 
-	// Initialize payment handler and routes
-	paymentHandler := handlers.NewPaymentHandler(config.StripeSecretKey)
-	routes.SetupPaymentRoutes(app, paymentHandler)
+	// Start background jobs
+	jobs.Start(config, paymentHandler)
 
 	// Start server
 	log.Printf("Server starting on port %s", config.Port)
@@ -1,6 +1,7 @@
 package configs
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
@@ -15,6 +16,27 @@ type CloudinaryConfig struct {
 	APISecret string
 }
 
+type CanadaPostConfig struct {
+	Username       string
+	Password       string
+	CustomerNumber string
+	BaseURL        string
+}
+
+type PayPalConfig struct {
+	ClientID     string
+	ClientSecret string
+	WebhookID    string // ID of the webhook registered with PayPal, needed to verify deliveries
+	BaseURL      string
+}
+
+// FakePaymentsConfig points the fake payment provider at our own webhook
+// endpoint
+type FakePaymentsConfig struct {
+	WebhookURL    string
+	WebhookSecret string
+}
+
 type SMTPConfig struct {
 	Host     string
 	Port     int
@@ -23,15 +45,31 @@ type SMTPConfig struct {
 }
 
 type Config struct {
-	DatabaseURL         string
-	JWTSecret           string
-	TokenExpiresIn      time.Duration
-	Cloudinary          CloudinaryConfig
-	SMTP                SMTPConfig
-	AppURL              string
-	StripeSecretKey     string
-	StripeWebhookSecret string
-	Port                string
+	DatabaseURL          string
+	JWTSecret            string
+	TokenExpiresIn       time.Duration
+	Cloudinary           CloudinaryConfig
+	SMTP                 SMTPConfig
+	AppURL               string
+	StripeSecretKey      string
+	StripeWebhookSecret  string
+	Port                 string
+	OfferExpiry          time.Duration // How long the other party has to answer an offer
+	OfferReservation     time.Duration // How long an accepted offer holds the listing
+	PaymentReservation   time.Duration // How long checkout holds products while payment is pending
+	MeetupReminderLead   time.Duration // How long before a meetup both parties are reminded
+	HandoffMaxAttempts   int           // Wrong handoff codes allowed before the seller is locked out
+	HandoffLockout       time.Duration // How long the seller is locked out
+	ShippingCarrier      string        // "canadapost", or "fake" for local development
+	IdempotencyRetention time.Duration // How long responses are kept for Idempotency-Key retries
+	PaymentProvider      string        // "stripe" (with PayPal), or "fake" for local development
+	PayoutProvider       string        // "stripe" (Connect), or "fake" for local development
+	PayoutMinimumCents   int64         // Smallest available balance worth paying out
+	PayoutInterval       time.Duration // How often available balances are paid out
+	CampusProvince       string        // Where meetups happen, for sales tax
+	CanadaPost           CanadaPostConfig
+	PayPal               PayPalConfig
+	FakePayments         FakePaymentsConfig
 }
 
 func LoadConfig() (*Config, error) {
@@ -75,10 +113,57 @@ func LoadConfig() (*Config, error) {
 			Username: getEnvOrDefault("SMTP_USERNAME", ""),
 			Password: getEnvOrDefault("SMTP_PASSWORD", ""),
 		},
-		AppURL:              getEnvOrDefault("APP_URL", "http://localhost:3000"),
-		StripeSecretKey:     getEnvOrDefault("STRIPE_SECRET_KEY", ""),
-		StripeWebhookSecret: getEnvOrDefault("STRIPE_WEBHOOK_SECRET", ""),
-		Port:                getEnvOrDefault("PORT", "8080"),
+		AppURL:               getEnvOrDefault("APP_URL", "http://localhost:3000"),
+		StripeSecretKey:      getEnvOrDefault("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret:  getEnvOrDefault("STRIPE_WEBHOOK_SECRET", ""),
+		Port:                 getEnvOrDefault("PORT", "8080"),
+		OfferExpiry:          time.Duration(getEnvAsInt("OFFER_EXPIRY_HOURS", 48)) * time.Hour,
+		OfferReservation:     time.Duration(getEnvAsInt("OFFER_RESERVATION_HOURS", 24)) * time.Hour,
+		PaymentReservation:   time.Duration(getEnvAsInt("PAYMENT_RESERVATION_MINUTES", 30)) * time.Minute,
+		MeetupReminderLead:   time.Duration(getEnvAsInt("MEETUP_REMINDER_MINUTES", 60)) * time.Minute,
+		HandoffMaxAttempts:   getEnvAsInt("HANDOFF_MAX_ATTEMPTS", 5),
+		HandoffLockout:       time.Duration(getEnvAsInt("HANDOFF_LOCKOUT_MINUTES", 15)) * time.Minute,
+		ShippingCarrier:      getEnvOrDefault("SHIPPING_CARRIER", "fake"),
+		IdempotencyRetention: time.Duration(getEnvAsInt("IDEMPOTENCY_RETENTION_HOURS", 24)) * time.Hour,
+		PaymentProvider:      getEnvOrDefault("PAYMENT_PROVIDER", "stripe"),
+		PayoutProvider:       getEnvOrDefault("PAYOUT_PROVIDER", "stripe"),
+		PayoutMinimumCents:   int64(getEnvAsInt("PAYOUT_MINIMUM_CENTS", 1000)),
+		PayoutInterval:       time.Duration(getEnvAsInt("PAYOUT_INTERVAL_HOURS", 24)) * time.Hour,
+		CampusProvince:       getEnvOrDefault("CAMPUS_PROVINCE", "ON"),
+		CanadaPost: CanadaPostConfig{
+			Username:       getEnvOrDefault("CANADA_POST_USERNAME", ""),
+			Password:       getEnvOrDefault("CANADA_POST_PASSWORD", ""),
+			CustomerNumber: getEnvOrDefault("CANADA_POST_CUSTOMER_NUMBER", ""),
+			BaseURL:        getEnvOrDefault("CANADA_POST_BASE_URL", "https://ct.soa-gw.canadapost.ca"),
+		},
+		PayPal: PayPalConfig{
+			ClientID:     getEnvOrDefault("PAYPAL_CLIENT_ID", ""),
+			ClientSecret: getEnvOrDefault("PAYPAL_CLIENT_SECRET", ""),
+			WebhookID:    getEnvOrDefault("PAYPAL_WEBHOOK_ID", ""),
+			BaseURL:      getEnvOrDefault("PAYPAL_BASE_URL", "https://api-m.sandbox.paypal.com"),
+		},
+	}
+	config.FakePayments = FakePaymentsConfig{
+		WebhookURL:    getEnvOrDefault("FAKE_PAYMENTS_WEBHOOK_URL", "http://localhost:"+config.Port+"/api/webhook/fake"),
+		WebhookSecret: getEnvOrDefault("FAKE_PAYMENTS_WEBHOOK_SECRET", "whsec_fake"),
+	}
+
+	switch config.ShippingCarrier {
+	case "canadapost", "fake", "":
+	default:
+		return nil, fmt.Errorf("unknown SHIPPING_CARRIER %q", config.ShippingCarrier)
+	}
+
+	switch config.PaymentProvider {
+	case "stripe", "fake":
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q", config.PaymentProvider)
+	}
+
+	switch config.PayoutProvider {
+	case "stripe", "fake":
+	default:
+		return nil, fmt.Errorf("unknown PAYOUT_PROVIDER %q", config.PayoutProvider)
 	}
 
 	return config, nil
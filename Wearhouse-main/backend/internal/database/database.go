@@ -30,15 +30,44 @@ func Connect(config *configs.Config) error {
 		return fmt.Errorf("failed to enable uuid-ossp extension: %w", err)
 	}
 
+	// Old amount columns have to be converted before AutoMigrate
+	if err := convertDecimalMoney(DB); err != nil {
+		log.Printf("Error converting amounts to cents: %v", err)
+		return err
+	}
+	if err := renameAmountCurrency(DB); err != nil {
+		log.Printf("Error renaming amount currencies: %v", err)
+		return err
+	}
+
 	// Auto-migrate models
 	if err := DB.AutoMigrate(
 		&models.User{},
+		&models.Address{},
+		&models.MeetupSpot{},
 		&models.Product{},
 		&models.Cart{},
 		&models.CartItem{},
+		&models.Checkout{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.MeetupProposal{},
 		&models.Payment{},
+		&models.Refund{},
+		&models.Dispute{},
+		&models.Shipment{},
+		&models.Offer{},
+		&models.IdempotencyKey{},
+		&models.WebhookEvent{},
+		&models.LedgerEntry{},
+		&models.Payout{},
+		&models.FeeSchedule{},
+		&models.FeeRule{},
+		&models.TaxRate{},
+		&models.TaxLine{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
 	); err != nil {
 		log.Printf("Error migrating database: %v", err)
 		return fmt.Errorf("failed to migrate database: %w", err)
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_products_brand_normalized;
+
+ALTER TABLE products DROP COLUMN IF EXISTS brand_normalized;
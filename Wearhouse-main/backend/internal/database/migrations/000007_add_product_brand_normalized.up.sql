@@ -0,0 +1,7 @@
+-- Add normalized brand column used for case-insensitive brand filtering
+ALTER TABLE products ADD COLUMN IF NOT EXISTS brand_normalized VARCHAR(100);
+
+-- Backfill existing products
+UPDATE products SET brand_normalized = LOWER(TRIM(brand)) WHERE brand_normalized IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_brand_normalized ON products(brand_normalized);
@@ -0,0 +1,7 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS offer_id;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS offer_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS reserved_until;
+ALTER TABLE products DROP COLUMN IF EXISTS reserved_for_id;
+
+DROP TABLE IF EXISTS offers;
@@ -0,0 +1,26 @@
+-- Create offers table
+CREATE TABLE IF NOT EXISTS offers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    product_id UUID NOT NULL REFERENCES products(id),
+    buyer_id UUID NOT NULL REFERENCES users(id),
+    seller_id UUID NOT NULL REFERENCES users(id),
+    amount DECIMAL(10,2) NOT NULL,
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_offers_product_id ON offers(product_id);
+CREATE INDEX IF NOT EXISTS idx_offers_buyer_id ON offers(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_offers_seller_id ON offers(seller_id);
+CREATE INDEX IF NOT EXISTS idx_offers_expires_at ON offers(expires_at);
+
+-- Hold a listing for the buyer whose offer was accepted
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved_for_id UUID REFERENCES users(id);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMP WITH TIME ZONE;
+
+-- Link cart and order items to the offer that set their price
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS offer_id UUID REFERENCES offers(id);
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS offer_id UUID REFERENCES offers(id);
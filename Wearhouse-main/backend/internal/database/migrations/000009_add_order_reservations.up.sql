@@ -0,0 +1,4 @@
+-- Track how long checkout holds an order's products while payment is pending
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reserved_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_pending_reserved_until ON orders(reserved_until) WHERE status = 'pending';
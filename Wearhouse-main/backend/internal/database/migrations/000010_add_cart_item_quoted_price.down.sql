@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS is_suspended;
+
+ALTER TABLE cart_items DROP COLUMN IF EXISTS quoted_price;
@@ -0,0 +1,11 @@
+-- Remember the price the buyer saw so checkout can report repricing
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS quoted_price DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+-- Backfill existing cart items with the current listing price
+UPDATE cart_items
+SET quoted_price = products.price
+FROM products
+WHERE cart_items.product_id = products.id AND cart_items.quoted_price = 0;
+
+-- Allow suspending users whose listings must not be bought
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_suspended BOOLEAN DEFAULT FALSE;
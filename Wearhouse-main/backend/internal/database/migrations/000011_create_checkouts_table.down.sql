@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_payments_checkout_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS checkout_id;
+
+DROP INDEX IF EXISTS idx_orders_seller_id;
+DROP INDEX IF EXISTS idx_orders_checkout_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS fulfilment_method;
+ALTER TABLE orders DROP COLUMN IF EXISTS seller_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS checkout_id;
+
+DROP TABLE IF EXISTS checkouts;
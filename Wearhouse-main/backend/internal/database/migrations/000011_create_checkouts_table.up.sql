@@ -0,0 +1,53 @@
+-- Create checkouts table grouping the per-seller orders of one cart
+CREATE TABLE IF NOT EXISTS checkouts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total DECIMAL(10,2) NOT NULL,
+    payment_method VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkouts_user_id ON checkouts(user_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS checkout_id UUID REFERENCES checkouts(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS seller_id UUID REFERENCES users(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fulfilment_method VARCHAR(20) NOT NULL DEFAULT 'ship';
+
+-- Give every existing order a checkout of its own, reusing the order ID
+INSERT INTO checkouts (id, user_id, status, total, payment_method, created_at, updated_at)
+SELECT id,
+       user_id,
+       CASE WHEN status = 'pending' THEN 'pending'
+            WHEN status = 'cancelled' THEN 'cancelled'
+            ELSE 'paid' END,
+       total,
+       payment_method,
+       created_at,
+       updated_at
+FROM orders
+WHERE checkout_id IS NULL;
+
+UPDATE orders SET checkout_id = id WHERE checkout_id IS NULL;
+
+-- Existing orders were placed with a single seller's products in mind
+UPDATE orders
+SET seller_id = (
+    SELECT products.user_id
+    FROM order_items
+    JOIN products ON products.id = order_items.product_id
+    WHERE order_items.order_id = orders.id
+    LIMIT 1
+)
+WHERE seller_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_checkout_id ON orders(checkout_id);
+CREATE INDEX IF NOT EXISTS idx_orders_seller_id ON orders(seller_id);
+
+-- Payments now cover a whole checkout
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS checkout_id UUID REFERENCES checkouts(id);
+UPDATE payments SET checkout_id = order_id WHERE checkout_id IS NULL;
+ALTER TABLE payments ALTER COLUMN order_id DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_checkout_id ON payments(checkout_id);
@@ -0,0 +1,17 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_addr_id,
+    DROP COLUMN IF EXISTS shipping_name,
+    DROP COLUMN IF EXISTS shipping_street,
+    DROP COLUMN IF EXISTS shipping_city,
+    DROP COLUMN IF EXISTS shipping_province,
+    DROP COLUMN IF EXISTS shipping_postal_code,
+    DROP COLUMN IF EXISTS shipping_phone,
+    DROP COLUMN IF EXISTS billing_addr_id,
+    DROP COLUMN IF EXISTS billing_name,
+    DROP COLUMN IF EXISTS billing_street,
+    DROP COLUMN IF EXISTS billing_city,
+    DROP COLUMN IF EXISTS billing_province,
+    DROP COLUMN IF EXISTS billing_postal_code,
+    DROP COLUMN IF EXISTS billing_phone;
+
+DROP TABLE IF EXISTS addresses;
@@ -0,0 +1,35 @@
+-- Create addresses table
+CREATE TABLE IF NOT EXISTS addresses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    label VARCHAR(50),
+    name VARCHAR(200),
+    street VARCHAR(255),
+    city VARCHAR(100),
+    province VARCHAR(2),
+    postal_code VARCHAR(7),
+    phone VARCHAR(20),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses(user_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_deleted_at ON addresses(deleted_at);
+
+-- Snapshot the addresses an order was placed with
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shipping_addr_id UUID REFERENCES addresses(id),
+    ADD COLUMN IF NOT EXISTS shipping_name VARCHAR(200),
+    ADD COLUMN IF NOT EXISTS shipping_street VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS shipping_city VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS shipping_province VARCHAR(2),
+    ADD COLUMN IF NOT EXISTS shipping_postal_code VARCHAR(7),
+    ADD COLUMN IF NOT EXISTS shipping_phone VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS billing_addr_id UUID REFERENCES addresses(id),
+    ADD COLUMN IF NOT EXISTS billing_name VARCHAR(200),
+    ADD COLUMN IF NOT EXISTS billing_street VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS billing_city VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS billing_province VARCHAR(2),
+    ADD COLUMN IF NOT EXISTS billing_postal_code VARCHAR(7),
+    ADD COLUMN IF NOT EXISTS billing_phone VARCHAR(20);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS meetup_proposals;
+
+DROP INDEX IF EXISTS idx_orders_meetup_at;
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS meetup_spot_id,
+    DROP COLUMN IF EXISTS meetup_at,
+    DROP COLUMN IF EXISTS meetup_reminded_at;
+
+DROP TABLE IF EXISTS meetup_spots;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,39 @@
+-- Admins manage the campus meetup spots
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN DEFAULT false;
+
+-- Create meetup_spots table
+CREATE TABLE IF NOT EXISTS meetup_spots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO meetup_spots (name, description) VALUES
+    ('MacOdrum Library', 'Main entrance, ground floor'),
+    ('University Centre (UC)', 'Atrium by the info desk');
+
+-- The agreed meetup of an order
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS meetup_spot_id UUID REFERENCES meetup_spots(id),
+    ADD COLUMN IF NOT EXISTS meetup_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS meetup_reminded_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_meetup_at ON orders(meetup_at);
+
+-- Create meetup_proposals table
+CREATE TABLE IF NOT EXISTS meetup_proposals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id),
+    spot_id UUID NOT NULL REFERENCES meetup_spots(id),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    proposed_by_id UUID NOT NULL REFERENCES users(id),
+    proposed_by VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'proposed',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_meetup_proposals_order_id ON meetup_proposals(order_id);
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS handoff_code,
+    DROP COLUMN IF EXISTS handoff_attempts,
+    DROP COLUMN IF EXISTS handoff_locked_at,
+    DROP COLUMN IF EXISTS handed_over_at;
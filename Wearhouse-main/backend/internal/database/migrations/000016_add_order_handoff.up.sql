@@ -0,0 +1,6 @@
+-- Codes that prove an order was handed over in person
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS handoff_code VARCHAR(6),
+    ADD COLUMN IF NOT EXISTS handoff_attempts INTEGER DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS handoff_locked_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS handed_over_at TIMESTAMP WITH TIME ZONE;
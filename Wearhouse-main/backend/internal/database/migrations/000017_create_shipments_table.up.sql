@@ -0,0 +1,18 @@
+-- Create shipments table
+CREATE TABLE IF NOT EXISTS shipments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id),
+    carrier VARCHAR(50) NOT NULL,
+    service_code VARCHAR(50),
+    tracking_number VARCHAR(100) NOT NULL,
+    label_url TEXT,
+    cost DECIMAL(10,2),
+    status VARCHAR(20) NOT NULL DEFAULT 'label_created',
+    status_detail TEXT,
+    last_checked_at TIMESTAMP WITH TIME ZONE,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_tracking_number ON shipments(tracking_number);
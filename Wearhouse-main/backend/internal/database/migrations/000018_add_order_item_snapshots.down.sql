@@ -0,0 +1,8 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS brand,
+    DROP COLUMN IF EXISTS size,
+    DROP COLUMN IF EXISTS condition,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS seller_id,
+    DROP COLUMN IF EXISTS seller_name;
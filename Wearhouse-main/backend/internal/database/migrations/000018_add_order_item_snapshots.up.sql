@@ -0,0 +1,22 @@
+-- Snapshot the listing on each order item so edits and deletion don't change past orders
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS title VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS brand VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS size VARCHAR(10),
+    ADD COLUMN IF NOT EXISTS condition VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS image_url TEXT,
+    ADD COLUMN IF NOT EXISTS seller_id UUID,
+    ADD COLUMN IF NOT EXISTS seller_name VARCHAR(200);
+
+-- Existing items get the listing as it is now, soft-deleted or not
+UPDATE order_items
+SET title = products.title,
+    brand = products.brand,
+    size = products.size,
+    condition = products.condition,
+    image_url = products.images[1],
+    seller_id = products.user_id,
+    seller_name = TRIM(users.first_name || ' ' || users.last_name)
+FROM products
+JOIN users ON users.id = products.user_id
+WHERE products.id = order_items.product_id;
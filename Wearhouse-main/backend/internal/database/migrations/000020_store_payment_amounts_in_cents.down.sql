@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_payments_checkout_id_status;
+
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS amount DECIMAL(10,2);
+UPDATE refunds SET amount = amount_cents / 100.0;
+ALTER TABLE refunds ALTER COLUMN amount SET NOT NULL;
+ALTER TABLE refunds DROP COLUMN IF EXISTS amount_cents;
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS amount DECIMAL(10,2);
+UPDATE payments SET amount = amount_cents / 100.0;
+ALTER TABLE payments ALTER COLUMN amount SET NOT NULL;
+ALTER TABLE payments DROP COLUMN IF EXISTS amount_cents;
@@ -0,0 +1,13 @@
+-- Store payment and refund amounts as integer cents
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS amount_cents BIGINT;
+UPDATE payments SET amount_cents = ROUND(amount * 100) WHERE amount_cents IS NULL;
+ALTER TABLE payments ALTER COLUMN amount_cents SET NOT NULL;
+ALTER TABLE payments DROP COLUMN IF EXISTS amount;
+
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS amount_cents BIGINT;
+UPDATE refunds SET amount_cents = ROUND(amount * 100) WHERE amount_cents IS NULL;
+ALTER TABLE refunds ALTER COLUMN amount_cents SET NOT NULL;
+ALTER TABLE refunds DROP COLUMN IF EXISTS amount;
+
+-- Find the pending intent of a checkout quickly
+CREATE INDEX IF NOT EXISTS idx_payments_checkout_id_status ON payments(checkout_id, status);
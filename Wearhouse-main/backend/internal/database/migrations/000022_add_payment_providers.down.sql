@@ -0,0 +1,4 @@
+ALTER TABLE disputes RENAME COLUMN provider_id TO stripe_dispute_id;
+ALTER TABLE refunds RENAME COLUMN provider_id TO stripe_refund_id;
+ALTER TABLE payments RENAME COLUMN provider_id TO stripe_id;
+ALTER TABLE payments DROP COLUMN IF EXISTS provider;
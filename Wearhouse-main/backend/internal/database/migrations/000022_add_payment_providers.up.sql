@@ -0,0 +1,5 @@
+-- Payments can be taken by providers other than Stripe
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS provider VARCHAR(20) NOT NULL DEFAULT 'stripe';
+ALTER TABLE payments RENAME COLUMN stripe_id TO provider_id;
+ALTER TABLE refunds RENAME COLUMN stripe_refund_id TO provider_id;
+ALTER TABLE disputes RENAME COLUMN stripe_dispute_id TO provider_id;
@@ -0,0 +1,2 @@
+-- Sellers choose per listing whether they take cash or e-Transfer at a meetup
+ALTER TABLE products ADD COLUMN IF NOT EXISTS accepts_cash BOOLEAN DEFAULT FALSE;
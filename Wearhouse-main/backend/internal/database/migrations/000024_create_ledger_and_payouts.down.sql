@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS payouts;
+ALTER TABLE users DROP COLUMN IF EXISTS payout_account_id;
@@ -0,0 +1,37 @@
+-- Sellers' accounts with the payout provider
+ALTER TABLE users ADD COLUMN IF NOT EXISTS payout_account_id VARCHAR(255);
+
+-- Create payouts table
+CREATE TABLE IF NOT EXISTS payouts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    amount_cents BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    provider_id VARCHAR(255),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_user_id ON payouts(user_id);
+
+-- Create the double-entry ledger, the entries of each transaction sum to zero
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL,
+    account VARCHAR(30) NOT NULL,
+    user_id UUID REFERENCES users(id),
+    order_id UUID REFERENCES orders(id),
+    payout_id UUID REFERENCES payouts(id),
+    amount_cents BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_user ON ledger_entries(account, user_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_order_id ON ledger_entries(order_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_payout_id ON ledger_entries(payout_id);
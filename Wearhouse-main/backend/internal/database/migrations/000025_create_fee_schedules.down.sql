@@ -0,0 +1,12 @@
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS fee_rule_id,
+    DROP COLUMN IF EXISTS fee_cents;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS fee_schedule_id,
+    DROP COLUMN IF EXISTS fee_cents;
+
+ALTER TABLE users DROP COLUMN IF EXISTS seller_tier;
+
+DROP TABLE IF EXISTS fee_rules;
+DROP TABLE IF EXISTS fee_schedules;
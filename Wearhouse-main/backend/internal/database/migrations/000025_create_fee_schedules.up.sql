@@ -0,0 +1,57 @@
+-- Create fee schedules table, each row is a version of the fee rules
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    version INTEGER NOT NULL UNIQUE,
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    note TEXT,
+    created_by_id UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fee_schedules_effective_from ON fee_schedules(effective_from);
+
+-- Create fee rules table, tried in order of position
+CREATE TABLE IF NOT EXISTS fee_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    schedule_id UUID NOT NULL REFERENCES fee_schedules(id),
+    position INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    listing_type VARCHAR(10),
+    category VARCHAR(50),
+    seller_tier VARCHAR(20),
+    min_price_cents BIGINT NOT NULL DEFAULT 0,
+    max_price_cents BIGINT NOT NULL DEFAULT 0,
+    percent_bps INTEGER NOT NULL DEFAULT 0,
+    fixed_cents BIGINT NOT NULL DEFAULT 0,
+    min_fee_cents BIGINT NOT NULL DEFAULT 0,
+    max_fee_cents BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_fee_rules_schedule_id ON fee_rules(schedule_id);
+
+-- The first schedule: 5% plus 30 cents, capped at $15, and less for pro
+-- sellers. Items under $5 pay a flat 25 cents.
+WITH schedule AS (
+    INSERT INTO fee_schedules (version, effective_from, note)
+    VALUES (1, CURRENT_TIMESTAMP, 'Launch fees')
+    RETURNING id
+)
+INSERT INTO fee_rules (schedule_id, position, name, seller_tier, min_price_cents, max_price_cents, percent_bps, fixed_cents, max_fee_cents)
+SELECT id, 0, 'Service fee', NULL, 0, 500, 0, 25, 0 FROM schedule
+UNION ALL
+SELECT id, 1, 'Service fee', 'pro', 500, 0, 300, 30, 1000 FROM schedule
+UNION ALL
+SELECT id, 2, 'Service fee', NULL, 500, 0, 500, 30, 1500 FROM schedule;
+
+-- Sellers' fee tier
+ALTER TABLE users ADD COLUMN IF NOT EXISTS seller_tier VARCHAR(20) NOT NULL DEFAULT 'standard';
+
+-- The fees charged on each order, and the schedule and rules they came from
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS fee_cents BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS fee_schedule_id UUID REFERENCES fee_schedules(id);
+
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS fee_cents BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS fee_rule_id UUID REFERENCES fee_rules(id);
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_cents;
+DROP TABLE IF EXISTS tax_lines;
+DROP TABLE IF EXISTS tax_rates;
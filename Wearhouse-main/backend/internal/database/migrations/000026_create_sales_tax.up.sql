@@ -0,0 +1,55 @@
+-- Create tax rates table, a new row for a tax replaces the old rate from
+-- its effective date
+CREATE TABLE IF NOT EXISTS tax_rates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    province VARCHAR(2) NOT NULL,
+    name VARCHAR(10) NOT NULL,
+    rate_ppm BIGINT NOT NULL,
+    applies_to_fees BOOLEAN NOT NULL DEFAULT TRUE,
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_rates_province_name ON tax_rates(province, name);
+
+-- GST everywhere, HST in the participating provinces, and the provincial
+-- sales taxes. BC PST and Manitoba RST don't apply to our fees.
+INSERT INTO tax_rates (province, name, rate_ppm, applies_to_fees, effective_from) VALUES
+    ('AB', 'GST', 50000, TRUE, '2008-01-01'),
+    ('BC', 'GST', 50000, TRUE, '2008-01-01'),
+    ('BC', 'PST', 70000, FALSE, '2013-04-01'),
+    ('MB', 'GST', 50000, TRUE, '2008-01-01'),
+    ('MB', 'RST', 70000, FALSE, '2019-07-01'),
+    ('NB', 'HST', 150000, TRUE, '2016-07-01'),
+    ('NL', 'HST', 150000, TRUE, '2016-07-01'),
+    ('NS', 'HST', 150000, TRUE, '2010-07-01'),
+    ('NS', 'HST', 140000, TRUE, '2025-04-01'),
+    ('NT', 'GST', 50000, TRUE, '2008-01-01'),
+    ('NU', 'GST', 50000, TRUE, '2008-01-01'),
+    ('ON', 'HST', 130000, TRUE, '2010-07-01'),
+    ('PE', 'HST', 150000, TRUE, '2016-10-01'),
+    ('QC', 'GST', 50000, TRUE, '2008-01-01'),
+    ('QC', 'QST', 99750, TRUE, '2013-01-01'),
+    ('SK', 'GST', 50000, TRUE, '2008-01-01'),
+    ('SK', 'PST', 60000, TRUE, '2017-03-23'),
+    ('YT', 'GST', 50000, TRUE, '2008-01-01');
+
+-- Create tax lines table, the taxes charged on each order item and its fee
+CREATE TABLE IF NOT EXISTS tax_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id),
+    order_item_id UUID NOT NULL REFERENCES order_items(id),
+    base VARCHAR(10) NOT NULL,
+    tax_rate_id UUID NOT NULL REFERENCES tax_rates(id),
+    name VARCHAR(10) NOT NULL,
+    province VARCHAR(2) NOT NULL,
+    rate_ppm BIGINT NOT NULL,
+    taxable_cents BIGINT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lines_order_id ON tax_lines(order_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_order_item_id ON tax_lines(order_item_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_cents BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,40 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price DECIMAL(10,2);
+UPDATE products SET price = price_cents / 100.0;
+ALTER TABLE products ALTER COLUMN price SET NOT NULL;
+ALTER TABLE products DROP COLUMN IF EXISTS price_currency;
+ALTER TABLE products DROP COLUMN IF EXISTS price_cents;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price DECIMAL(10,2);
+UPDATE order_items SET price = price_cents / 100.0;
+ALTER TABLE order_items ALTER COLUMN price SET NOT NULL;
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_currency;
+ALTER TABLE order_items DROP COLUMN IF EXISTS price_cents;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total DECIMAL(10,2);
+UPDATE orders SET total = total_cents / 100.0;
+ALTER TABLE orders ALTER COLUMN total SET NOT NULL;
+ALTER TABLE orders DROP COLUMN IF EXISTS total_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS total_cents;
+
+ALTER TABLE checkouts ADD COLUMN IF NOT EXISTS total DECIMAL(10,2);
+UPDATE checkouts SET total = total_cents / 100.0;
+ALTER TABLE checkouts ALTER COLUMN total SET NOT NULL;
+ALTER TABLE checkouts DROP COLUMN IF EXISTS total_currency;
+ALTER TABLE checkouts DROP COLUMN IF EXISTS total_cents;
+
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS quoted_price DECIMAL(10,2);
+UPDATE cart_items SET quoted_price = quoted_price_cents / 100.0;
+ALTER TABLE cart_items ALTER COLUMN quoted_price SET NOT NULL;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS quoted_price_currency;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS quoted_price_cents;
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS amount DECIMAL(10,2);
+UPDATE offers SET amount = amount_cents / 100.0;
+ALTER TABLE offers ALTER COLUMN amount SET NOT NULL;
+ALTER TABLE offers DROP COLUMN IF EXISTS amount_currency;
+ALTER TABLE offers DROP COLUMN IF EXISTS amount_cents;
+
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS cost DECIMAL(10,2);
+UPDATE shipments SET cost = cost_cents / 100.0;
+ALTER TABLE shipments DROP COLUMN IF EXISTS cost_currency;
+ALTER TABLE shipments DROP COLUMN IF EXISTS cost_cents;
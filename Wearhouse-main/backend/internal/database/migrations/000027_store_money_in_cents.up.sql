@@ -0,0 +1,50 @@
+-- Store prices and totals as integer cents with their currency
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_cents BIGINT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE products SET price_cents = ROUND(COALESCE(price, 0) * 100);
+ALTER TABLE products ALTER COLUMN price_cents SET DEFAULT 0;
+ALTER TABLE products ALTER COLUMN price_cents SET NOT NULL;
+ALTER TABLE products DROP COLUMN IF EXISTS price;
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price_cents BIGINT;
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS price_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE order_items SET price_cents = ROUND(COALESCE(price, 0) * 100);
+ALTER TABLE order_items ALTER COLUMN price_cents SET DEFAULT 0;
+ALTER TABLE order_items ALTER COLUMN price_cents SET NOT NULL;
+ALTER TABLE order_items DROP COLUMN IF EXISTS price;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_cents BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE orders SET total_cents = ROUND(COALESCE(total, 0) * 100);
+ALTER TABLE orders ALTER COLUMN total_cents SET DEFAULT 0;
+ALTER TABLE orders ALTER COLUMN total_cents SET NOT NULL;
+ALTER TABLE orders DROP COLUMN IF EXISTS total;
+
+ALTER TABLE checkouts ADD COLUMN IF NOT EXISTS total_cents BIGINT;
+ALTER TABLE checkouts ADD COLUMN IF NOT EXISTS total_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE checkouts SET total_cents = ROUND(COALESCE(total, 0) * 100);
+ALTER TABLE checkouts ALTER COLUMN total_cents SET DEFAULT 0;
+ALTER TABLE checkouts ALTER COLUMN total_cents SET NOT NULL;
+ALTER TABLE checkouts DROP COLUMN IF EXISTS total;
+
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS quoted_price_cents BIGINT;
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS quoted_price_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE cart_items SET quoted_price_cents = ROUND(COALESCE(quoted_price, 0) * 100);
+ALTER TABLE cart_items ALTER COLUMN quoted_price_cents SET DEFAULT 0;
+ALTER TABLE cart_items ALTER COLUMN quoted_price_cents SET NOT NULL;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS quoted_price;
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS amount_cents BIGINT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS amount_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE offers SET amount_cents = ROUND(COALESCE(amount, 0) * 100);
+ALTER TABLE offers ALTER COLUMN amount_cents SET DEFAULT 0;
+ALTER TABLE offers ALTER COLUMN amount_cents SET NOT NULL;
+ALTER TABLE offers DROP COLUMN IF EXISTS amount;
+
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS cost_cents BIGINT;
+ALTER TABLE shipments ADD COLUMN IF NOT EXISTS cost_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+UPDATE shipments SET cost_cents = ROUND(COALESCE(cost, 0) * 100);
+ALTER TABLE shipments ALTER COLUMN cost_cents SET DEFAULT 0;
+ALTER TABLE shipments ALTER COLUMN cost_cents SET NOT NULL;
+ALTER TABLE shipments DROP COLUMN IF EXISTS cost;
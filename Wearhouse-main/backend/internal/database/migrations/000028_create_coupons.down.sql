@@ -0,0 +1,10 @@
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_cents;
+
+DROP INDEX IF EXISTS idx_orders_coupon_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_cents;
+ALTER TABLE orders DROP COLUMN IF EXISTS coupon_id;
+
+ALTER TABLE carts DROP COLUMN IF EXISTS coupon_id;
+
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,49 @@
+-- Create coupons table, promo codes buyers apply to their cart. Empty
+-- scopes cover anything.
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    type VARCHAR(10) NOT NULL,
+    percent_bps INTEGER NOT NULL DEFAULT 0,
+    amount_cents BIGINT NOT NULL DEFAULT 0,
+    amount_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    max_discount_cents BIGINT NOT NULL DEFAULT 0,
+    max_discount_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    min_spend_cents BIGINT NOT NULL DEFAULT 0,
+    min_spend_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    university VARCHAR(255),
+    category VARCHAR(50),
+    seller_id UUID REFERENCES users(id),
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    max_uses_per_user INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by_id UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupons_seller_id ON coupons(seller_id);
+
+-- Create coupon redemptions table, one use of a coupon per checkout
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    coupon_id UUID NOT NULL REFERENCES coupons(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    checkout_id UUID NOT NULL UNIQUE REFERENCES checkouts(id),
+    discount_cents BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_id ON coupon_redemptions(coupon_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user_id ON coupon_redemptions(user_id);
+
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES coupons(id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_id UUID REFERENCES coupons(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_cents BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_orders_coupon_id ON orders(coupon_id);
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount_cents BIGINT NOT NULL DEFAULT 0;
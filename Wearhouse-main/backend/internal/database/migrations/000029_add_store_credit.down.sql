@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS refund_as_credit;
+ALTER TABLE orders DROP COLUMN IF EXISTS credit_cents;
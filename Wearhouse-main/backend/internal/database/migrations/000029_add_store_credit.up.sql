@@ -0,0 +1,5 @@
+-- Store credit is kept in the ledger. Orders record how much of their total
+-- was paid from the buyer's wallet, and whether a cancellation refunded it
+-- there rather than to their card.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS credit_cents BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_as_credit BOOLEAN DEFAULT FALSE;
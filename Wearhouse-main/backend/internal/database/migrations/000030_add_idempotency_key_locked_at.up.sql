@@ -0,0 +1,3 @@
+-- When the running request claimed the key, so a retry can take over a key
+-- whose request died before answering
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
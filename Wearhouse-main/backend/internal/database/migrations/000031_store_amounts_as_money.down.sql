@@ -0,0 +1,22 @@
+ALTER TABLE tax_lines
+    DROP COLUMN IF EXISTS taxable_currency,
+    DROP COLUMN IF EXISTS amount_currency;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS fee_currency,
+    DROP COLUMN IF EXISTS tax_currency;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS fee_currency;
+
+ALTER TABLE fee_rules
+    DROP COLUMN IF EXISTS min_price_currency,
+    DROP COLUMN IF EXISTS max_price_currency,
+    DROP COLUMN IF EXISTS fixed_currency,
+    DROP COLUMN IF EXISTS min_fee_currency,
+    DROP COLUMN IF EXISTS max_fee_currency;
+
+ALTER TABLE payouts RENAME COLUMN amount_currency TO currency;
+ALTER TABLE ledger_entries RENAME COLUMN amount_currency TO currency;
+ALTER TABLE disputes RENAME COLUMN amount_currency TO currency;
+ALTER TABLE refunds RENAME COLUMN amount_currency TO currency;
+ALTER TABLE payments RENAME COLUMN amount_currency TO currency;
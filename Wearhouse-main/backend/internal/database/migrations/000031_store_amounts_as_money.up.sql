@@ -0,0 +1,24 @@
+-- Every amount is stored as <name>_cents with its <name>_currency
+
+ALTER TABLE payments RENAME COLUMN currency TO amount_currency;
+ALTER TABLE refunds RENAME COLUMN currency TO amount_currency;
+ALTER TABLE disputes RENAME COLUMN currency TO amount_currency;
+ALTER TABLE ledger_entries RENAME COLUMN currency TO amount_currency;
+ALTER TABLE payouts RENAME COLUMN currency TO amount_currency;
+
+ALTER TABLE fee_rules
+    ADD COLUMN IF NOT EXISTS min_price_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS max_price_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS fixed_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS min_fee_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS max_fee_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS fee_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS fee_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS tax_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+
+ALTER TABLE tax_lines
+    ADD COLUMN IF NOT EXISTS taxable_currency VARCHAR(3) NOT NULL DEFAULT 'cad',
+    ADD COLUMN IF NOT EXISTS amount_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
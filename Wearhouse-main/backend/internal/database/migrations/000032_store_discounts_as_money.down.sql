@@ -0,0 +1,3 @@
+ALTER TABLE coupon_redemptions DROP COLUMN IF EXISTS discount_currency;
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_currency;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_currency;
@@ -0,0 +1,4 @@
+-- Coupon discounts are stored with their currency like every other amount
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
+ALTER TABLE coupon_redemptions ADD COLUMN IF NOT EXISTS discount_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
@@ -0,0 +1,2 @@
+-- Store credit paid on an order is stored with its currency like every other amount
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS credit_currency VARCHAR(3) NOT NULL DEFAULT 'cad';
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// decimalMoneyColumns are the amounts that were stored as decimal dollars
+// before they became money.Money. Each is now a <column>_cents and
+// <column>_currency pair.
+var decimalMoneyColumns = []struct {
+	table  string
+	column string
+}{
+	{"products", "price"},
+	{"order_items", "price"},
+	{"orders", "total"},
+	{"checkouts", "total"},
+	{"cart_items", "quoted_price"},
+	{"offers", "amount"},
+	{"shipments", "cost"},
+}
+
+// convertDecimalMoney moves amounts still stored as decimal dollars into
+// cents. It must run before AutoMigrate, which would otherwise add the cents
+// columns filled with zeros. It does nothing once the old columns are gone.
+func convertDecimalMoney(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, amount := range decimalMoneyColumns {
+		if !migrator.HasTable(amount.table) || !migrator.HasColumn(amount.table, amount.column) {
+			continue
+		}
+
+		// The cents column may already be there, zeroed by an AutoMigrate
+		// that ran first, so it is filled in from the old column regardless
+		cents, currency := amount.column+"_cents", amount.column+"_currency"
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, sql := range []string{
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s BIGINT`, amount.table, cents),
+				fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s VARCHAR(3) NOT NULL DEFAULT 'cad'`, amount.table, currency),
+				fmt.Sprintf(`UPDATE %s SET %s = ROUND(COALESCE(%s, 0) * 100)`, amount.table, cents, amount.column),
+				fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s SET DEFAULT 0, ALTER COLUMN %s SET NOT NULL`, amount.table, cents, cents),
+				fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, amount.table, amount.column),
+			} {
+				if err := tx.Exec(sql).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to convert %s.%s to cents: %w", amount.table, amount.column, err)
+		}
+	}
+	return nil
+}
+
+// amountCurrencyTables kept the currency of their amount_cents in a plain
+// currency column before the amount became money.Money
+var amountCurrencyTables = []string{"payments", "refunds", "disputes", "ledger_entries", "payouts"}
+
+// renameAmountCurrency renames currency to amount_currency. Like
+// convertDecimalMoney it must run before AutoMigrate, which would otherwise
+// add amount_currency next to the NOT NULL currency that inserts no longer
+// fill. If AutoMigrate already added it, the old column's values are copied
+// over and it is dropped.
+func renameAmountCurrency(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, table := range amountCurrencyTables {
+		if !migrator.HasTable(table) || !migrator.HasColumn(table, "currency") {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(table, "amount_currency") {
+				return tx.Exec(fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN currency TO amount_currency`, table)).Error
+			}
+			for _, sql := range []string{
+				fmt.Sprintf(`UPDATE %s SET amount_currency = currency`, table),
+				fmt.Sprintf(`ALTER TABLE %s DROP COLUMN currency`, table),
+			} {
+				if err := tx.Exec(sql).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to rename %s.currency: %w", table, err)
+		}
+	}
+	return nil
+}
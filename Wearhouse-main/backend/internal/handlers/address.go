@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func init() {
+	validate.RegisterValidation("province", func(fl validator.FieldLevel) bool {
+		province := fl.Field().String()
+		for _, p := range models.Provinces {
+			if p == province {
+				return true
+			}
+		}
+		return false
+	})
+	validate.RegisterValidation("postal_code_ca", func(fl validator.FieldLevel) bool {
+		return models.IsValidPostalCode(fl.Field().String())
+	})
+	validate.RegisterValidation("phone_ca", func(fl validator.FieldLevel) bool {
+		return models.NormalizePhone(fl.Field().String()) != ""
+	})
+}
+
+type AddressHandler struct {
+	config *configs.Config
+}
+
+func NewAddressHandler(config *configs.Config) *AddressHandler {
+	return &AddressHandler{
+		config: config,
+	}
+}
+
+// GetAddresses lists the user's address book
+func (h *AddressHandler) GetAddresses(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var addresses []models.Address
+	if err := database.DB.Where("user_id = ?", claims.UserID).
+		Order("created_at asc").Find(&addresses).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get addresses")
+	}
+
+	response := make([]types.AddressResponse, len(addresses))
+	for i := range addresses {
+		response[i] = addressToResponse(&addresses[i])
+	}
+
+	return c.JSON(response)
+}
+
+// GetAddress returns one of the user's addresses
+func (h *AddressHandler) GetAddress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	address, err := findAddress(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(addressToResponse(address))
+}
+
+// CreateAddress adds an address to the user's address book
+func (h *AddressHandler) CreateAddress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	req, err := parseAddressRequest(c)
+	if err != nil {
+		return err
+	}
+
+	address := models.Address{
+		UserID: claims.UserID,
+	}
+	applyAddressRequest(&address, req)
+
+	if err := database.DB.Create(&address).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create address")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(addressToResponse(&address))
+}
+
+// UpdateAddress replaces one of the user's addresses. Orders already placed
+// keep the address they were shipped to.
+func (h *AddressHandler) UpdateAddress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	address, err := findAddress(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	req, err := parseAddressRequest(c)
+	if err != nil {
+		return err
+	}
+	applyAddressRequest(address, req)
+
+	if err := database.DB.Save(address).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update address")
+	}
+
+	return c.JSON(addressToResponse(address))
+}
+
+// DeleteAddress removes an address from the user's address book
+func (h *AddressHandler) DeleteAddress(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	address, err := findAddress(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Delete(address).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete address")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// findAddress loads an address that belongs to userID
+func findAddress(id string, userID uuid.UUID) (*models.Address, error) {
+	addressID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid address ID")
+	}
+
+	var address models.Address
+	if err := database.DB.Where("id = ? AND user_id = ?", addressID, userID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Address not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to get address")
+	}
+
+	return &address, nil
+}
+
+// parseAddressRequest reads and validates an address from the request body
+func parseAddressRequest(c *fiber.Ctx) (*types.AddressRequest, error) {
+	var req types.AddressRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	req.Province = strings.ToUpper(strings.TrimSpace(req.Province))
+
+	if err := validate.Struct(req); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+		}
+		switch fe := fieldErrs[0]; {
+		case fe.Field() == "Province" && fe.Tag() == "province":
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Province must be one of "+strings.Join(models.Provinces, ", "))
+		case fe.Field() == "PostalCode" && fe.Tag() == "postal_code_ca":
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid postal code")
+		case fe.Field() == "Phone":
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid phone number")
+		case fe.Tag() == "required":
+			return nil, fiber.NewError(fiber.StatusBadRequest, fe.Field()+" is required")
+		default:
+			return nil, fiber.NewError(fiber.StatusBadRequest, fe.Field()+" is too long")
+		}
+	}
+
+	return &req, nil
+}
+
+// applyAddressRequest copies a validated request onto an address
+func applyAddressRequest(address *models.Address, req *types.AddressRequest) {
+	address.Label = strings.TrimSpace(req.Label)
+	address.PostalAddress = models.PostalAddress{
+		Name:       strings.TrimSpace(req.Name),
+		Street:     strings.TrimSpace(req.Street),
+		City:       strings.TrimSpace(req.City),
+		Province:   req.Province,
+		PostalCode: models.NormalizePostalCode(req.PostalCode),
+		Phone:      models.NormalizePhone(req.Phone),
+	}
+}
+
+func addressToResponse(address *models.Address) types.AddressResponse {
+	return types.AddressResponse{
+		ID:                    address.ID,
+		Label:                 address.Label,
+		PostalAddressResponse: postalAddressToResponse(address.PostalAddress),
+		CreatedAt:             address.CreatedAt,
+		UpdatedAt:             address.UpdatedAt,
+	}
+}
+
+func postalAddressToResponse(address models.PostalAddress) types.PostalAddressResponse {
+	return types.PostalAddressResponse{
+		Name:       address.Name,
+		Street:     address.Street,
+		City:       address.City,
+		Province:   address.Province,
+		PostalCode: address.PostalCode,
+		Phone:      address.Phone,
+	}
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"log"
+	"time"
 	"wearhouse/internal/database"
 	"wearhouse/internal/models"
+	"wearhouse/internal/money"
 	"wearhouse/internal/types"
 	"wearhouse/internal/utils"
 
@@ -18,10 +21,7 @@ func GetCart(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*utils.JWTClaims)
 
 	// Get or create cart
-	var cart models.Cart
-	err := database.DB.Preload("Items.Product").FirstOrCreate(&cart, models.Cart{
-		UserID: claims.UserID,
-	}).Error
+	cart, err := loadCart(claims.UserID)
 	if err != nil {
 		log.Printf("Error getting cart: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -29,34 +29,59 @@ func GetCart(c *fiber.Ctx) error {
 		})
 	}
 
-	// Calculate total
-	var total float64
-	for _, item := range cart.Items {
-		total += item.Product.Price * float64(item.Quantity)
+	return c.JSON(cartToResponse(cart))
+}
+
+// ValidateCart reconciles the cart against the live listings and reports
+// every item that was removed, sold, repriced or whose seller was suspended
+// since the buyer added it. With "acknowledge": true the changes are applied
+// to the cart so checkout can proceed.
+func ValidateCart(c *fiber.Ctx) error {
+	// Get user from context
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.ValidateCartRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
 	}
 
-	// Convert to response
-	response := types.CartResponse{
-		ID:        cart.ID,
-		UserID:    cart.UserID,
-		Items:     make([]types.CartItemResponse, len(cart.Items)),
-		Total:     total,
-		CreatedAt: cart.CreatedAt,
-		UpdatedAt: cart.UpdatedAt,
+	cart, err := loadCart(claims.UserID)
+	if err != nil {
+		log.Printf("Error getting cart: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get cart",
+		})
 	}
 
-	for i, item := range cart.Items {
-		response.Items[i] = types.CartItemResponse{
-			ID:        item.ID,
-			ProductID: item.ProductID,
-			Product:   *toProductResponse(&item.Product),
-			Quantity:  item.Quantity,
-			CreatedAt: item.CreatedAt,
-			UpdatedAt: item.UpdatedAt,
+	changes := validateCartItems(cart.Items, claims.UserID)
+	if req.Acknowledge && len(changes) > 0 {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			return acknowledgeCartChanges(tx, cart.Items, changes)
+		}); err != nil {
+			log.Printf("Error applying cart changes: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update cart",
+			})
+		}
+
+		if cart, err = loadCart(claims.UserID); err != nil {
+			log.Printf("Error getting cart: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get cart",
+			})
 		}
 	}
 
-	return c.JSON(response)
+	return c.JSON(types.CartValidationResponse{
+		Valid:        len(changes) == 0,
+		Acknowledged: req.Acknowledge && len(changes) > 0,
+		Changes:      changes,
+		Cart:         *cartToResponse(cart),
+	})
 }
 
 // AddToCart adds a product to the cart
@@ -71,6 +96,18 @@ func AddToCart(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
+
+	// Every listing is a single unique item
+	if req.Quantity > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only one of each item is available",
+		})
+	}
 
 	// Validate product exists
 	var product models.Product
@@ -92,6 +129,13 @@ func AddToCart(c *fiber.Ctx) error {
 		})
 	}
 
+	// Check if product is held for another buyer
+	if product.IsReservedForOther(claims.UserID, time.Now()) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is reserved for another buyer",
+		})
+	}
+
 	// Get or create cart
 	var cart models.Cart
 	err := database.DB.FirstOrCreate(&cart, models.Cart{
@@ -108,20 +152,15 @@ func AddToCart(c *fiber.Ctx) error {
 	var cartItem models.CartItem
 	err = database.DB.Where("cart_id = ? AND product_id = ?", cart.ID, req.ProductID).First(&cartItem).Error
 	if err == nil {
-		// Update quantity
-		cartItem.Quantity += req.Quantity
-		if err := database.DB.Save(&cartItem).Error; err != nil {
-			log.Printf("Error updating cart item: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update cart item",
-			})
-		}
+		// Already in the cart, and there is only one of it
+		return GetCart(c)
 	} else if err == gorm.ErrRecordNotFound {
 		// Create new cart item
 		cartItem = models.CartItem{
-			CartID:    cart.ID,
-			ProductID: req.ProductID,
-			Quantity:  req.Quantity,
+			CartID:      cart.ID,
+			ProductID:   req.ProductID,
+			Quantity:    req.Quantity,
+			QuotedPrice: product.Price,
 		}
 		if err := database.DB.Create(&cartItem).Error; err != nil {
 			log.Printf("Error creating cart item: %v", err)
@@ -159,6 +198,11 @@ func UpdateCartItem(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request data",
+		})
+	}
 
 	// Get cart item
 	var cartItem models.CartItem
@@ -181,6 +225,13 @@ func UpdateCartItem(c *fiber.Ctx) error {
 		})
 	}
 
+	// Every listing is a single unique item
+	if req.Quantity > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Only one of each item is available",
+		})
+	}
+
 	// Update or delete based on quantity
 	if req.Quantity > 0 {
 		cartItem.Quantity = req.Quantity
@@ -276,3 +327,133 @@ func ClearCart(c *fiber.Ctx) error {
 
 	return GetCart(c)
 }
+
+// loadCart gets or creates the user's cart with everything needed to price
+// and validate it. Deleted products are loaded too so they can be reported.
+func loadCart(userID uuid.UUID) (*models.Cart, error) {
+	var cart models.Cart
+	err := database.DB.
+		Preload("Items.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Items.Product.User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Items.Offer").
+		Preload("Coupon").
+		FirstOrCreate(&cart, models.Cart{UserID: userID}).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// validateCartItems compares each cart item with its live listing
+func validateCartItems(items []models.CartItem, userID uuid.UUID) []types.CartItemChange {
+	changes := []types.CartItemChange{}
+	now := time.Now()
+
+	for _, item := range items {
+		change := types.CartItemChange{
+			ItemID:    item.ID,
+			ProductID: item.ProductID,
+			Title:     item.Product.Title,
+		}
+
+		switch {
+		case item.Product.ID == uuid.Nil || item.Product.DeletedAt.Valid:
+			change.Reason = types.CartChangeRemoved
+		case item.Product.User.IsSuspended || item.Product.User.DeletedAt.Valid:
+			change.Reason = types.CartChangeSellerSuspended
+		case !item.Product.IsAvailable || item.Product.IsReservedForOther(userID, now):
+			change.Reason = types.CartChangeUnavailable
+		case item.UnitPrice().Cents != item.QuotedPrice.Cents:
+			oldPrice, newPrice := item.QuotedPrice, item.UnitPrice()
+			change.Reason = types.CartChangePriceChanged
+			change.OldPrice = &oldPrice
+			change.NewPrice = &newPrice
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// acknowledgeCartChanges drops the items that can no longer be bought and
+// accepts the new price of the ones that were repriced
+func acknowledgeCartChanges(tx *gorm.DB, items []models.CartItem, changes []types.CartItemChange) error {
+	for _, change := range changes {
+		if change.Reason == types.CartChangePriceChanged {
+			if err := tx.Model(&models.CartItem{}).Where("id = ?", change.ItemID).
+				Updates(models.CartItem{QuotedPrice: *change.NewPrice}).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := tx.Delete(&models.CartItem{}, "id = ?", change.ItemID).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Helper function to convert Cart model to CartResponse
+func cartToResponse(cart *models.Cart) *types.CartResponse {
+	// Calculate total
+	subtotal := money.Cents(0)
+	for _, item := range cart.Items {
+		subtotal = subtotal.Add(item.UnitPrice().Mul(item.Quantity))
+	}
+
+	response := &types.CartResponse{
+		ID:        cart.ID,
+		UserID:    cart.UserID,
+		Items:     make([]types.CartItemResponse, len(cart.Items)),
+		Subtotal:  subtotal,
+		Discount:  money.Cents(0),
+		Total:     subtotal,
+		CreatedAt: cart.CreatedAt,
+		UpdatedAt: cart.UpdatedAt,
+	}
+
+	// The coupon stays on the cart when it stops applying, e.g. when the
+	// items it covered are removed, so the buyer is told why
+	if cart.Coupon != nil {
+		response.Coupon = &types.CartCouponResponse{
+			Code:        cart.Coupon.Code,
+			Description: cart.Coupon.Description,
+		}
+		discounts, err := couponDiscounts(database.DB, cart.Coupon, cart.UserID, cart.Items, time.Now())
+		var fiberErr *fiber.Error
+		switch {
+		case errors.As(err, &fiberErr):
+			response.Coupon.Problem = fiberErr.Message
+		case err != nil:
+			log.Printf("Error checking coupon %s: %v", cart.Coupon.Code, err)
+			response.Coupon.Problem = "Coupon could not be checked"
+		default:
+			discount := money.Cents(0)
+			for _, amount := range discounts {
+				discount = discount.Add(amount)
+			}
+			response.Discount = discount
+			response.Total = subtotal.Sub(discount)
+		}
+	}
+
+	for i, item := range cart.Items {
+		response.Items[i] = types.CartItemResponse{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Product:   *toProductResponse(&item.Product),
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice(),
+			OfferID:   item.OfferID,
+			CreatedAt: item.CreatedAt,
+			UpdatedAt: item.UpdatedAt,
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponHandler struct {
+	config *configs.Config
+}
+
+func NewCouponHandler(config *configs.Config) *CouponHandler {
+	return &CouponHandler{
+		config: config,
+	}
+}
+
+// GetCoupons lists every coupon and how often it was used, newest first
+// (admin)
+func (h *CouponHandler) GetCoupons(c *fiber.Ctx) error {
+	var coupons []models.Coupon
+	if err := database.DB.Order("created_at desc").Find(&coupons).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get coupons")
+	}
+
+	var uses []struct {
+		CouponID uuid.UUID
+		Uses     int64
+	}
+	if err := database.DB.Model(&models.CouponRedemption{}).
+		Select("coupon_id, COUNT(*) AS uses").Group("coupon_id").Scan(&uses).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get coupon uses")
+	}
+	usesByCoupon := make(map[uuid.UUID]int64, len(uses))
+	for _, u := range uses {
+		usesByCoupon[u.CouponID] = u.Uses
+	}
+
+	response := make([]types.CouponResponse, len(coupons))
+	for i := range coupons {
+		response[i] = couponToResponse(&coupons[i], usesByCoupon[coupons[i].ID])
+	}
+
+	return c.JSON(response)
+}
+
+// CreateCoupon adds a promo code (admin)
+func (h *CouponHandler) CreateCoupon(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CreateCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	coupon := models.Coupon{
+		Code:           models.NormalizeCouponCode(req.Code),
+		Description:    strings.TrimSpace(req.Description),
+		Type:           models.CouponType(req.Type),
+		PercentBPS:     req.PercentBPS,
+		Amount:         req.Amount,
+		MaxDiscount:    req.MaxDiscount,
+		MinSpend:       req.MinSpend,
+		University:     strings.TrimSpace(req.University),
+		Category:       strings.TrimSpace(req.Category),
+		SellerID:       req.SellerID,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: 1,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		IsActive:       true,
+		CreatedByID:    &claims.UserID,
+	}
+	if req.MaxUsesPerUser != nil {
+		coupon.MaxUsesPerUser = *req.MaxUsesPerUser
+	}
+	switch coupon.Type {
+	case models.CouponPercent:
+		if coupon.PercentBPS == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Percent coupons need a percentage off")
+		}
+	case models.CouponFixed:
+		if coupon.Amount.IsZero() {
+			return fiber.NewError(fiber.StatusBadRequest, "Fixed coupons need an amount off")
+		}
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return fiber.NewError(fiber.StatusBadRequest, "Coupons must end after they start")
+	}
+	if coupon.SellerID != nil {
+		var count int64
+		if err := database.DB.Model(&models.User{}).Where("id = ?", *coupon.SellerID).Count(&count).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get seller")
+		}
+		if count == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Seller not found")
+		}
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.Coupon{}).Where("code = ?", coupon.Code).Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to check coupon code")
+	}
+	if count > 0 {
+		return fiber.NewError(fiber.StatusConflict, "A coupon with this code already exists")
+	}
+
+	if err := database.DB.Create(&coupon).Error; err != nil {
+		log.Printf("Error creating coupon: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create coupon")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(couponToResponse(&coupon, 0))
+}
+
+// DeactivateCoupon stops a coupon from being used (admin). It is kept for
+// the orders that used it.
+func (h *CouponHandler) DeactivateCoupon(c *fiber.Ctx) error {
+	couponID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid coupon ID")
+	}
+
+	var coupon models.Coupon
+	if err := database.DB.First(&coupon, "id = ?", couponID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Coupon not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get coupon")
+	}
+	if err := database.DB.Model(&coupon).Update("is_active", false).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to deactivate coupon")
+	}
+
+	uses, err := couponUses(database.DB, coupon.ID, nil)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get coupon uses")
+	}
+
+	return c.JSON(couponToResponse(&coupon, uses))
+}
+
+// ApplyCoupon puts a promo code on the buyer's cart. It is refused if it
+// takes nothing off the cart as it is.
+func ApplyCoupon(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.ApplyCouponRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	var coupon models.Coupon
+	if err := database.DB.First(&coupon, "code = ?", models.NormalizeCouponCode(req.Code)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Coupon not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get coupon")
+	}
+
+	cart, err := loadCart(claims.UserID)
+	if err != nil {
+		log.Printf("Error getting cart: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get cart")
+	}
+	if _, err := couponDiscounts(database.DB, &coupon, claims.UserID, cart.Items, time.Now()); err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error checking coupon %s: %v", coupon.Code, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to check coupon")
+	}
+
+	if err := database.DB.Model(cart).Update("coupon_id", coupon.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to apply coupon")
+	}
+
+	return GetCart(c)
+}
+
+// RemoveCoupon takes the promo code off the buyer's cart
+func RemoveCoupon(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	if err := database.DB.Model(&models.Cart{}).Where("user_id = ?", claims.UserID).
+		Update("coupon_id", nil).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to remove coupon")
+	}
+
+	return GetCart(c)
+}
+
+// couponDiscounts works out what coupon takes off each of a buyer's cart
+// items, by cart item ID. The discount is shared between the items the
+// coupon covers in proportion to their price. It returns a fiber error
+// saying why if the buyer can't use the coupon on these items.
+func couponDiscounts(db *gorm.DB, coupon *models.Coupon, userID uuid.UUID, items []models.CartItem, now time.Time) (map[uuid.UUID]money.Money, error) {
+	if !coupon.IsRedeemableAt(now) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Coupon "+coupon.Code+" is not valid at the moment")
+	}
+
+	if coupon.University != "" {
+		var buyer models.User
+		if err := db.Select("university").First(&buyer, "id = ?", userID).Error; err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(coupon.University, buyer.University) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Coupon "+coupon.Code+" is only for students at "+coupon.University)
+		}
+	}
+
+	if err := checkCouponUses(db, coupon, userID); err != nil {
+		return nil, err
+	}
+
+	eligible := money.Cents(0)
+	for i := range items {
+		if coupon.Covers(&items[i].Product) {
+			eligible = eligible.Add(items[i].UnitPrice().Mul(items[i].Quantity))
+		}
+	}
+	if eligible.IsZero() {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Coupon "+coupon.Code+" doesn't apply to anything in your cart")
+	}
+	if eligible.Cents < coupon.MinSpend.Cents {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Coupon %s needs a spend of at least $%s", coupon.Code, coupon.MinSpend))
+	}
+
+	// The last item takes what is left after rounding
+	total := coupon.Discount(eligible)
+	discounts := make(map[uuid.UUID]money.Money)
+	remaining, left := total.Cents, eligible.Cents
+	for i := range items {
+		if !coupon.Covers(&items[i].Product) {
+			continue
+		}
+		price := items[i].UnitPrice().Mul(items[i].Quantity).Cents
+		share := remaining
+		if left > price {
+			share = remaining * price / left
+		}
+		discounts[items[i].ID] = money.Money{Cents: share, Currency: total.Currency}
+		remaining -= share
+		left -= price
+	}
+	return discounts, nil
+}
+
+// checkCouponUses fails if the coupon has been used up, by everyone or by
+// the buyer
+func checkCouponUses(db *gorm.DB, coupon *models.Coupon, userID uuid.UUID) error {
+	if coupon.MaxUses > 0 {
+		uses, err := couponUses(db, coupon.ID, nil)
+		if err != nil {
+			return err
+		}
+		if uses >= int64(coupon.MaxUses) {
+			return fiber.NewError(fiber.StatusBadRequest, "Coupon "+coupon.Code+" has been used up")
+		}
+	}
+	if coupon.MaxUsesPerUser > 0 {
+		uses, err := couponUses(db, coupon.ID, &userID)
+		if err != nil {
+			return err
+		}
+		if uses >= int64(coupon.MaxUsesPerUser) {
+			return fiber.NewError(fiber.StatusBadRequest, "You have already used coupon "+coupon.Code)
+		}
+	}
+	return nil
+}
+
+// couponUses counts the checkouts a coupon was used on, by userID if set
+func couponUses(db *gorm.DB, couponID uuid.UUID, userID *uuid.UUID) (int64, error) {
+	query := db.Model(&models.CouponRedemption{}).Where("coupon_id = ?", couponID)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	var uses int64
+	err := query.Count(&uses).Error
+	return uses, err
+}
+
+// redeemCoupon records the use of a coupon on a checkout. The coupon is
+// locked first so concurrent checkouts can't use it beyond its limits.
+func redeemCoupon(tx *gorm.DB, couponID, userID, checkoutID uuid.UUID, discount money.Money) error {
+	var coupon models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, "id = ?", couponID).Error; err != nil {
+		return err
+	}
+	if err := checkCouponUses(tx, &coupon, userID); err != nil {
+		return err
+	}
+	return tx.Create(&models.CouponRedemption{
+		CouponID:   coupon.ID,
+		UserID:     userID,
+		CheckoutID: checkoutID,
+		Discount:   discount,
+	}).Error
+}
+
+func couponToResponse(coupon *models.Coupon, uses int64) types.CouponResponse {
+	response := types.CouponResponse{
+		ID:             coupon.ID,
+		Code:           coupon.Code,
+		Description:    coupon.Description,
+		Type:           string(coupon.Type),
+		PercentBPS:     coupon.PercentBPS,
+		MinSpend:       coupon.MinSpend,
+		University:     coupon.University,
+		Category:       coupon.Category,
+		SellerID:       coupon.SellerID,
+		MaxUses:        coupon.MaxUses,
+		MaxUsesPerUser: coupon.MaxUsesPerUser,
+		Uses:           uses,
+		StartsAt:       coupon.StartsAt,
+		EndsAt:         coupon.EndsAt,
+		IsActive:       coupon.IsActive,
+		CreatedAt:      coupon.CreatedAt,
+	}
+	if !coupon.Amount.IsZero() {
+		response.Amount = &coupon.Amount
+	}
+	if !coupon.MaxDiscount.IsZero() {
+		response.MaxDiscount = &coupon.MaxDiscount
+	}
+	return response
+}
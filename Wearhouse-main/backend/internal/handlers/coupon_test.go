@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+func TestCouponDiscountsShareTheRemainder(t *testing.T) {
+	item := func(cents int64, quantity int, listing models.ListingType) models.CartItem {
+		return models.CartItem{
+			ID:       uuid.New(),
+			Quantity: quantity,
+			Product:  models.Product{ListingType: listing, Price: money.Cents(cents)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		items  []models.CartItem
+		want   []int64 // Per item, in cart order
+	}{
+		{
+			name:   "split in proportion to price",
+			coupon: models.Coupon{Type: models.CouponFixed, Amount: money.Cents(600)},
+			items:  []models.CartItem{item(1000, 1, models.Sale), item(2000, 1, models.Sale)},
+			want:   []int64{200, 400},
+		},
+		{
+			name:   "last item takes the rounding",
+			coupon: models.Coupon{Type: models.CouponFixed, Amount: money.Cents(100)},
+			items:  []models.CartItem{item(1000, 1, models.Sale), item(1000, 1, models.Sale), item(1000, 1, models.Sale)},
+			want:   []int64{33, 33, 34},
+		},
+		{
+			name:   "quantity counts towards the share",
+			coupon: models.Coupon{Type: models.CouponPercent, PercentBPS: 1000},
+			items:  []models.CartItem{item(999, 3, models.Sale), item(1, 1, models.Sale)},
+			want:   []int64{299, 1},
+		},
+		{
+			name:   "items the coupon doesn't cover get nothing",
+			coupon: models.Coupon{Type: models.CouponFixed, Amount: money.Cents(500)},
+			items:  []models.CartItem{item(0, 1, models.Free), item(1500, 1, models.Sale)},
+			want:   []int64{0, 500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Coupons without a university or use limits don't need the database
+			tt.coupon.Code = "TEST"
+			tt.coupon.IsActive = true
+			discounts, err := couponDiscounts(nil, &tt.coupon, uuid.New(), tt.items, time.Now())
+			if err != nil {
+				t.Fatalf("couponDiscounts: %v", err)
+			}
+
+			var sum, eligible int64
+			for i, cartItem := range tt.items {
+				got := discounts[cartItem.ID].Cents
+				if got != tt.want[i] {
+					t.Errorf("item %d discount = %d cents, want %d", i, got, tt.want[i])
+				}
+				sum += got
+				if tt.coupon.Covers(&cartItem.Product) {
+					eligible += cartItem.UnitPrice().Mul(cartItem.Quantity).Cents
+				}
+			}
+			if total := tt.coupon.Discount(money.Cents(eligible)).Cents; sum != total {
+				t.Errorf("discounts add up to %d cents, want %d", sum, total)
+			}
+		})
+	}
+}
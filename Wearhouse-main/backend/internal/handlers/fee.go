@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FeeHandler struct {
+	config *configs.Config
+}
+
+func NewFeeHandler(config *configs.Config) *FeeHandler {
+	return &FeeHandler{
+		config: config,
+	}
+}
+
+// GetFeeSchedules lists every version of the fee rules, newest first (admin)
+func (h *FeeHandler) GetFeeSchedules(c *fiber.Ctx) error {
+	var schedules []models.FeeSchedule
+	if err := database.DB.Preload("Rules", withFeeRuleOrder).
+		Order("version desc").Find(&schedules).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get fee schedules")
+	}
+
+	response := make([]types.FeeScheduleResponse, len(schedules))
+	for i := range schedules {
+		response[i] = feeScheduleToResponse(&schedules[i])
+	}
+
+	return c.JSON(response)
+}
+
+// CreateFeeSchedule publishes a new version of the fee rules (admin). Past
+// versions are kept unchanged, so orders placed under them keep their fees.
+func (h *FeeHandler) CreateFeeSchedule(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CreateFeeScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	now := time.Now()
+	effectiveFrom := now
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now) {
+			return fiber.NewError(fiber.StatusBadRequest, "Fee schedules can't take effect in the past")
+		}
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	schedule := models.FeeSchedule{
+		EffectiveFrom: effectiveFrom,
+		Note:          req.Note,
+		CreatedByID:   &claims.UserID,
+	}
+	for i, rule := range req.Rules {
+		if !rule.MaxPrice.IsZero() && rule.MaxPrice.Cents <= rule.MinPrice.Cents {
+			return fiber.NewError(fiber.StatusBadRequest, "Rule "+rule.Name+" has an empty price band")
+		}
+		if !rule.MaxFee.IsZero() && rule.MaxFee.Cents < rule.MinFee.Cents {
+			return fiber.NewError(fiber.StatusBadRequest, "Rule "+rule.Name+" caps its fee below its minimum")
+		}
+		schedule.Rules = append(schedule.Rules, models.FeeRule{
+			Position:    i,
+			Name:        strings.TrimSpace(rule.Name),
+			ListingType: models.ListingType(rule.ListingType),
+			Category:    strings.TrimSpace(rule.Category),
+			SellerTier:  models.SellerTier(rule.SellerTier),
+			MinPrice:    rule.MinPrice,
+			MaxPrice:    rule.MaxPrice,
+			PercentBPS:  rule.PercentBPS,
+			Fixed:       rule.Fixed,
+			MinFee:      rule.MinFee,
+			MaxFee:      rule.MaxFee,
+		})
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Versions are numbered one after the other
+		if err := tx.Exec("LOCK TABLE fee_schedules IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.FeeSchedule{}).Select("COALESCE(MAX(version), 0) + 1").
+			Scan(&schedule.Version).Error; err != nil {
+			return err
+		}
+		return tx.Create(&schedule).Error
+	})
+	if err != nil {
+		log.Printf("Error creating fee schedule: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create fee schedule")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(feeScheduleToResponse(&schedule))
+}
+
+// UpdateSellerTier moves a seller to another fee tier (admin). Only orders
+// placed from then on are charged under it.
+func (h *FeeHandler) UpdateSellerTier(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req types.SellerTierRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	result := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("seller_tier", req.Tier)
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update seller tier")
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// currentFeeSchedule returns the fee schedule in effect now, or nil if
+// fees have never been set up
+func currentFeeSchedule(db *gorm.DB) (*models.FeeSchedule, error) {
+	var schedule models.FeeSchedule
+	err := db.Preload("Rules", withFeeRuleOrder).
+		Where("effective_from <= ?", time.Now()).
+		Order("effective_from desc, version desc").
+		First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// withFeeRuleOrder sorts fee rules in the order they are tried
+func withFeeRuleOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc")
+}
+
+func feeScheduleToResponse(schedule *models.FeeSchedule) types.FeeScheduleResponse {
+	rules := make([]types.FeeRuleResponse, len(schedule.Rules))
+	for i, rule := range schedule.Rules {
+		rules[i] = types.FeeRuleResponse{
+			ID:          rule.ID,
+			Position:    rule.Position,
+			Name:        rule.Name,
+			ListingType: string(rule.ListingType),
+			Category:    rule.Category,
+			SellerTier:  string(rule.SellerTier),
+			MinPrice:    rule.MinPrice,
+			MaxPrice:    rule.MaxPrice,
+			PercentBPS:  rule.PercentBPS,
+			Fixed:       rule.Fixed,
+			MinFee:      rule.MinFee,
+			MaxFee:      rule.MaxFee,
+		}
+	}
+
+	return types.FeeScheduleResponse{
+		ID:            schedule.ID,
+		Version:       schedule.Version,
+		EffectiveFrom: schedule.EffectiveFrom,
+		Note:          schedule.Note,
+		CreatedByID:   schedule.CreatedByID,
+		Rules:         rules,
+		CreatedAt:     schedule.CreatedAt,
+	}
+}
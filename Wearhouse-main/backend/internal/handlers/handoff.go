@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// handoffURL prefixes the QR payload of a handoff code
+const handoffURL = "wearhouse://handoff"
+
+// GetHandoffCode returns the code the buyer shows the seller when an item is
+// handed over in person. The code is created the first time it's asked for.
+func (h *OrderHandler) GetHandoffCode(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var order models.Order
+	if err := database.DB.Where("id = ? AND user_id = ?", orderID, claims.UserID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order")
+	}
+	if err := checkHandoff(database.DB, &order); err != nil {
+		return err
+	}
+
+	if order.HandoffCode == "" {
+		code, err := utils.GenerateHandoffCode()
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate handoff code")
+		}
+
+		// Keep the first code if two requests race
+		result := database.DB.Model(&models.Order{}).
+			Where("id = ? AND (handoff_code IS NULL OR handoff_code = '')", order.ID).
+			Update("handoff_code", code)
+		if result.Error != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save handoff code")
+		}
+		if result.RowsAffected == 0 {
+			if err := database.DB.First(&order, "id = ?", order.ID).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order")
+			}
+		} else {
+			order.HandoffCode = code
+		}
+	}
+
+	return c.JSON(types.HandoffCodeResponse{
+		OrderID:   order.ID,
+		Code:      order.HandoffCode,
+		QRPayload: fmt.Sprintf("%s?order=%s&code=%s", handoffURL, order.ID, order.HandoffCode),
+	})
+}
+
+// ConfirmHandoff lets the seller confirm an in-person handoff with the code
+// the buyer shows them. A valid code delivers the order, and for cash orders
+// also confirms the seller was paid; too many wrong codes lock the seller
+// out for a while.
+func (h *OrderHandler) ConfirmHandoff(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req types.HandoffRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	code, err := parseHandoffCode(req.Code, orderID)
+	if err != nil {
+		return err
+	}
+
+	// A wrong code is still recorded, so it is reported after the commit
+	var rejected error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND seller_id = ?", orderID, claims.UserID).First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Order not found")
+			}
+			return err
+		}
+		if err := checkHandoff(tx, &order); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if order.HandoffLockedAt != nil {
+			if now.Before(order.HandoffLockedAt.Add(h.config.HandoffLockout)) {
+				return fiber.NewError(fiber.StatusTooManyRequests, "Too many wrong handoff codes, try again later")
+			}
+			// The lockout is over, start counting again
+			order.HandoffAttempts = 0
+			order.HandoffLockedAt = nil
+		}
+
+		if order.HandoffCode == "" {
+			return fiber.NewError(fiber.StatusConflict, "The buyer hasn't opened their handoff code yet")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(code), []byte(order.HandoffCode)) != 1 {
+			updates := map[string]interface{}{
+				"handoff_attempts":  order.HandoffAttempts + 1,
+				"handoff_locked_at": nil,
+			}
+			rejected = fiber.NewError(fiber.StatusBadRequest, "Invalid handoff code")
+			if order.HandoffAttempts+1 >= h.config.HandoffMaxAttempts {
+				updates["handoff_attempts"] = 0
+				updates["handoff_locked_at"] = now
+				rejected = fiber.NewError(fiber.StatusTooManyRequests, "Too many wrong handoff codes, try again later")
+			}
+			return tx.Model(&order).Updates(updates).Error
+		}
+
+		if err := tx.Model(&order).Updates(map[string]interface{}{
+			"handed_over_at":    now,
+			"handoff_attempts":  0,
+			"handoff_locked_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		if order.Status == models.OrderStatusAwaitingHandoff {
+			if err := settleCashOrder(tx, &order); err != nil {
+				return err
+			}
+		}
+
+		return transitionOrder(tx, &order, models.OrderStatusDelivered, models.ActorSystem, nil, "Handed over in person")
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to confirm handoff")
+	}
+	if rejected != nil {
+		return rejected
+	}
+
+	order, err := findSale(orderID.String(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(orderToResponse(order))
+}
+
+// checkHandoff makes sure an order is handed over in person and is waiting
+// to be. Meetup orders and trades are.
+func checkHandoff(db *gorm.DB, order *models.Order) error {
+	if order.FulfilmentMethod != models.FulfilmentMeetup {
+		var trades int64
+		if err := db.Model(&models.OrderItem{}).
+			Joins("JOIN products ON products.id = order_items.product_id").
+			Where("order_items.order_id = ? AND products.listing_type = ?", order.ID, models.Trade).
+			Count(&trades).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order items")
+		}
+		if trades == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Order is not handed over in person")
+		}
+	}
+
+	switch order.Status {
+	case models.OrderStatusAwaitingHandoff, models.OrderStatusPaid, models.OrderStatusReady:
+		return nil
+	default:
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Order can't be handed over while it is %s", order.Status))
+	}
+}
+
+// settleCashOrder records the cash the seller received at the handoff and
+// marks the order paid. The checkout is paid once none of its orders are
+// waiting for payment any more.
+func settleCashOrder(tx *gorm.DB, order *models.Order) error {
+	payment := models.Payment{
+		CheckoutID:    order.CheckoutID,
+		OrderID:       &order.ID,
+		Amount:        order.Total,
+		Status:        string(types.PaymentStatusSuccess),
+		PaymentMethod: models.PaymentMethodCashOnMeetup,
+		Provider:      "cash",
+		ProviderID:    "cash_" + order.ID.String(),
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	if err := transitionOrder(tx, order, models.OrderStatusPaid, models.ActorSystem, nil, "Paid in cash at the meetup"); err != nil {
+		return err
+	}
+
+	open, err := countUnpaidOrders(tx, order.CheckoutID)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+	return tx.Model(&models.Checkout{}).
+		Where("id = ? AND status = ?", order.CheckoutID, models.CheckoutStatusPending).
+		Update("status", models.CheckoutStatusPaid).Error
+}
+
+// parseHandoffCode returns the code the seller entered, which is either the
+// code itself or the payload of the buyer's QR code
+func parseHandoffCode(input string, orderID uuid.UUID) (string, error) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, handoffURL) {
+		return input, nil
+	}
+
+	payload, err := url.Parse(input)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid handoff code")
+	}
+	if payload.Query().Get("order") != orderID.String() {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Handoff code is for a different order")
+	}
+
+	return payload.Query().Get("code"), nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ledgerLine is one side of a ledger posting
+type ledgerLine struct {
+	account models.LedgerAccount
+	userID  *uuid.UUID
+	amount  int64
+}
+
+// postLedger records a balanced set of ledger lines as one transaction.
+// Zero lines are left out, and nothing is written if all of them are zero.
+func postLedger(tx *gorm.DB, orderID, payoutID *uuid.UUID, description string, lines ...ledgerLine) error {
+	entries, err := ledgerEntries(orderID, payoutID, description, lines)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(&entries).Error
+}
+
+// ledgerEntries turns ledger lines into the entries of one transaction. It
+// fails unless the lines add up to zero.
+func ledgerEntries(orderID, payoutID *uuid.UUID, description string, lines []ledgerLine) ([]models.LedgerEntry, error) {
+	transactionID := uuid.New()
+	var sum int64
+	var entries []models.LedgerEntry
+	for _, line := range lines {
+		sum += line.amount
+		if line.amount == 0 {
+			continue
+		}
+		entries = append(entries, models.LedgerEntry{
+			TransactionID: transactionID,
+			Account:       line.account,
+			UserID:        line.userID,
+			OrderID:       orderID,
+			PayoutID:      payoutID,
+			Amount:        money.Cents(line.amount),
+			Description:   description,
+		})
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("ledger posting %q is off by %d cents", description, sum)
+	}
+	return entries, nil
+}
+
+// recordSale books an order paid online. The buyer's money is held for the
+// seller until the order is delivered, apart from the platform fee and the
+// sales tax we collect. We make up the coupon discount, if any, so the
+// seller is held their full price. Store credit the buyer paid with comes
+// out of their wallet.
+func recordSale(tx *gorm.DB, order *models.Order) error {
+	return postLedger(tx, &order.ID, nil, "Order paid", saleLines(order)...)
+}
+
+// saleLines are the ledger lines recordSale books for an order
+func saleLines(order *models.Order) []ledgerLine {
+	total := order.Total.Cents
+	return []ledgerLine{
+		{account: models.LedgerBuyerCharges, amount: -(total - order.Credit.Cents)},
+		{account: models.LedgerStoreCredit, userID: &order.UserID, amount: -order.Credit.Cents},
+		{account: models.LedgerPromotions, amount: -order.Discount.Cents},
+		{account: models.LedgerPlatformFees, amount: order.Fee.Cents},
+		{account: models.LedgerSalesTax, amount: order.Tax.Cents},
+		{account: models.LedgerSellerPending, userID: &order.SellerID, amount: total + order.Discount.Cents - order.Fee.Cents - order.Tax.Cents},
+	}
+}
+
+// releaseEscrow makes what an order earned the seller available for payout.
+// Orders paid outside the app have nothing held.
+func releaseEscrow(tx *gorm.DB, order *models.Order) error {
+	held, err := orderLedgerBalance(tx, order.ID, models.LedgerSellerPending)
+	if err != nil {
+		return err
+	}
+	return postLedger(tx, &order.ID, nil, "Order delivered",
+		ledgerLine{account: models.LedgerSellerPending, userID: &order.SellerID, amount: -held},
+		ledgerLine{account: models.LedgerSellerAvailable, userID: &order.SellerID, amount: held},
+	)
+}
+
+// reverseSale undoes whatever was booked for an order that was cancelled
+// before it was delivered, as the buyer gets their money back. Store credit
+// goes back to their wallet, and so does what they paid online if they
+// chose to be refunded in credit.
+func reverseSale(tx *gorm.DB, order *models.Order) error {
+	var lines []ledgerLine
+	for _, account := range []models.LedgerAccount{
+		models.LedgerBuyerCharges,
+		models.LedgerStoreCredit,
+		models.LedgerPromotions,
+		models.LedgerPlatformFees,
+		models.LedgerSalesTax,
+		models.LedgerSellerPending,
+	} {
+		balance, err := orderLedgerBalance(tx, order.ID, account)
+		if err != nil {
+			return err
+		}
+		line := ledgerLine{account: account, amount: -balance}
+		switch account {
+		case models.LedgerBuyerCharges:
+			if order.RefundAsCredit {
+				line = ledgerLine{account: models.LedgerStoreCredit, userID: &order.UserID, amount: -balance}
+			}
+		case models.LedgerStoreCredit:
+			line.userID = &order.UserID
+		case models.LedgerSellerPending:
+			line.userID = &order.SellerID
+		}
+		lines = append(lines, line)
+	}
+	return postLedger(tx, &order.ID, nil, "Order cancelled", lines...)
+}
+
+// orderLedgerBalance is what an order has left in an account
+func orderLedgerBalance(tx *gorm.DB, orderID uuid.UUID, account models.LedgerAccount) (int64, error) {
+	var balance int64
+	err := tx.Model(&models.LedgerEntry{}).
+		Where("order_id = ? AND account = ?", orderID, account).
+		Select("COALESCE(SUM(amount_cents), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// userLedgerBalance is what a user has in one of their accounts
+func userLedgerBalance(tx *gorm.DB, userID uuid.UUID, account models.LedgerAccount) (int64, error) {
+	var balance int64
+	err := tx.Model(&models.LedgerEntry{}).
+		Where("user_id = ? AND account = ?", userID, account).
+		Select("COALESCE(SUM(amount_cents), 0)").Scan(&balance).Error
+	return balance, err
+}
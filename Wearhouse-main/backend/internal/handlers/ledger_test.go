@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"testing"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+func TestSaleLinesBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		order       models.Order
+		wantPending int64 // Held for the seller
+		wantEntries int
+	}{
+		{
+			name:        "price only",
+			order:       models.Order{Total: money.Cents(2500)},
+			wantPending: 2500,
+			wantEntries: 2,
+		},
+		{
+			name:        "fee and tax",
+			order:       models.Order{Total: money.Cents(2938), Fee: money.Cents(150), Tax: money.Cents(288)},
+			wantPending: 2500,
+			wantEntries: 4,
+		},
+		{
+			name:        "coupon paid for by the platform",
+			order:       models.Order{Total: money.Cents(2188), Fee: money.Cents(150), Tax: money.Cents(288), Discount: money.Cents(750)},
+			wantPending: 2500,
+			wantEntries: 5,
+		},
+		{
+			name:        "part paid with store credit",
+			order:       models.Order{Total: money.Cents(2938), Fee: money.Cents(150), Tax: money.Cents(288), Credit: money.Cents(1000)},
+			wantPending: 2500,
+			wantEntries: 5,
+		},
+		{
+			name:        "all paid with store credit",
+			order:       models.Order{Total: money.Cents(2938), Fee: money.Cents(150), Tax: money.Cents(288), Credit: money.Cents(2938)},
+			wantPending: 2500,
+			wantEntries: 4,
+		},
+		{
+			name:        "free listing",
+			order:       models.Order{Total: money.Cents(0)},
+			wantPending: 0,
+			wantEntries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.order.ID = uuid.New()
+			tt.order.UserID = uuid.New()
+			tt.order.SellerID = uuid.New()
+
+			lines := saleLines(&tt.order)
+			var sum int64
+			for _, line := range lines {
+				sum += line.amount
+				if line.account == models.LedgerSellerPending && line.amount != tt.wantPending {
+					t.Errorf("seller pending = %d, want %d", line.amount, tt.wantPending)
+				}
+			}
+			if sum != 0 {
+				t.Errorf("lines add up to %d, want 0", sum)
+			}
+
+			entries, err := ledgerEntries(&tt.order.ID, nil, "Order paid", lines)
+			if err != nil {
+				t.Fatalf("ledgerEntries: %v", err)
+			}
+			if len(entries) != tt.wantEntries {
+				t.Errorf("got %d entries, want %d", len(entries), tt.wantEntries)
+			}
+			for _, entry := range entries {
+				if entry.TransactionID != entries[0].TransactionID {
+					t.Errorf("entries are spread over several transactions")
+				}
+			}
+		})
+	}
+}
+
+func TestLedgerEntriesRejectsUnbalancedLines(t *testing.T) {
+	sellerID := uuid.New()
+	_, err := ledgerEntries(nil, nil, "Payout", []ledgerLine{
+		{account: models.LedgerSellerAvailable, userID: &sellerID, amount: -1000},
+		{account: models.LedgerPayouts, userID: &sellerID, amount: 999},
+	})
+	if err == nil {
+		t.Fatal("unbalanced lines were accepted")
+	}
+}
@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MeetupHandler struct {
+	config *configs.Config
+}
+
+func NewMeetupHandler(config *configs.Config) *MeetupHandler {
+	return &MeetupHandler{
+		config: config,
+	}
+}
+
+// GetMeetupSpots lists the spots buyers and sellers can currently meet at
+func (h *MeetupHandler) GetMeetupSpots(c *fiber.Ctx) error {
+	var spots []models.MeetupSpot
+	if err := database.DB.Where("is_active = ?", true).Order("name asc").Find(&spots).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get meetup spots")
+	}
+
+	response := make([]types.MeetupSpotResponse, len(spots))
+	for i := range spots {
+		response[i] = meetupSpotToResponse(&spots[i])
+	}
+
+	return c.JSON(response)
+}
+
+// GetAllMeetupSpots lists every meetup spot, including inactive ones (admin)
+func (h *MeetupHandler) GetAllMeetupSpots(c *fiber.Ctx) error {
+	var spots []models.MeetupSpot
+	if err := database.DB.Order("name asc").Find(&spots).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get meetup spots")
+	}
+
+	response := make([]types.MeetupSpotResponse, len(spots))
+	for i := range spots {
+		response[i] = meetupSpotToResponse(&spots[i])
+	}
+
+	return c.JSON(response)
+}
+
+// CreateMeetupSpot adds a campus meetup spot (admin)
+func (h *MeetupHandler) CreateMeetupSpot(c *fiber.Ctx) error {
+	var req types.MeetupSpotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	spot := models.MeetupSpot{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		spot.IsActive = *req.IsActive
+	}
+
+	if err := database.DB.Create(&spot).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create meetup spot")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(meetupSpotToResponse(&spot))
+}
+
+// UpdateMeetupSpot changes a meetup spot (admin)
+func (h *MeetupHandler) UpdateMeetupSpot(c *fiber.Ctx) error {
+	spot, err := findMeetupSpot(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	var req types.MeetupSpotRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	spot.Name = strings.TrimSpace(req.Name)
+	spot.Description = req.Description
+	if req.IsActive != nil {
+		spot.IsActive = *req.IsActive
+	}
+
+	if err := database.DB.Save(spot).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update meetup spot")
+	}
+
+	return c.JSON(meetupSpotToResponse(spot))
+}
+
+// DeleteMeetupSpot retires a meetup spot (admin). Orders that already met
+// there keep it, so it is only deactivated.
+func (h *MeetupHandler) DeleteMeetupSpot(c *fiber.Ctx) error {
+	spot, err := findMeetupSpot(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(spot).Update("is_active", false).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete meetup spot")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ProposeMeetup suggests a time and spot to hand over a meetup order. It
+// replaces any proposals the other party left open.
+func (h *OrderHandler) ProposeMeetup(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req types.ProposeMeetupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+	if !req.StartsAt.After(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Meetup time must be in the future")
+	}
+
+	order, actor, err := findOrderForParty(orderID, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkMeetupOpen(order); err != nil {
+		return err
+	}
+
+	spot, err := findMeetupSpot(req.SpotID.String())
+	if err != nil {
+		return err
+	}
+	if !spot.IsActive {
+		return fiber.NewError(fiber.StatusBadRequest, "Meetup spot is no longer available")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Proposing is a counter to whatever the other party suggested
+		if err := tx.Model(&models.MeetupProposal{}).
+			Where("order_id = ? AND status = ? AND proposed_by <> ?", order.ID, models.MeetupProposalOpen, actor).
+			Update("status", models.MeetupProposalSuperseded).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.MeetupProposal{
+			OrderID:      order.ID,
+			SpotID:       spot.ID,
+			StartsAt:     req.StartsAt,
+			ProposedByID: claims.UserID,
+			ProposedBy:   actor,
+			Status:       models.MeetupProposalOpen,
+		}).Error
+	})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to propose meetup")
+	}
+
+	return h.respondWithOrder(c, order.ID)
+}
+
+// AcceptMeetup agrees to one of the other party's proposals
+func (h *OrderHandler) AcceptMeetup(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	order, proposal, err := findMeetupProposalToAnswer(c, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !proposal.StartsAt.After(time.Now()) {
+		return fiber.NewError(fiber.StatusConflict, "Proposed meetup time has passed")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Everything else on the table, including an earlier agreement, is replaced
+		if err := tx.Model(&models.MeetupProposal{}).
+			Where("order_id = ? AND id <> ? AND status IN ?", order.ID, proposal.ID, []models.MeetupProposalStatus{
+				models.MeetupProposalOpen,
+				models.MeetupProposalAccepted,
+			}).
+			Update("status", models.MeetupProposalSuperseded).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(proposal).Update("status", models.MeetupProposalAccepted).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(order).Updates(map[string]interface{}{
+			"meetup_spot_id":     proposal.SpotID,
+			"meetup_at":          proposal.StartsAt,
+			"meetup_reminded_at": nil,
+		}).Error
+	})
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to accept meetup")
+	}
+
+	return h.respondWithOrder(c, order.ID)
+}
+
+// DeclineMeetup turns down one of the other party's proposals
+func (h *OrderHandler) DeclineMeetup(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	order, proposal, err := findMeetupProposalToAnswer(c, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(proposal).Update("status", models.MeetupProposalDeclined).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to decline meetup")
+	}
+
+	return h.respondWithOrder(c, order.ID)
+}
+
+// respondWithOrder reloads an order and writes it to the response
+func (h *OrderHandler) respondWithOrder(c *fiber.Ctx, orderID uuid.UUID) error {
+	var order models.Order
+	if err := database.DB.Scopes(withOrderDetails).First(&order, "id = ?", orderID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load order")
+	}
+
+	return c.JSON(orderToResponse(&order))
+}
+
+// findMeetupProposalToAnswer loads the order and open proposal named in the
+// URL, making sure userID is the party who has to answer it
+func findMeetupProposalToAnswer(c *fiber.Ctx, userID uuid.UUID) (*models.Order, *models.MeetupProposal, error) {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+	proposalID, err := uuid.Parse(c.Params("proposalId"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid proposal ID")
+	}
+
+	order, actor, err := findOrderForParty(orderID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkMeetupOpen(order); err != nil {
+		return nil, nil, err
+	}
+
+	var proposal models.MeetupProposal
+	if err := database.DB.Where("id = ? AND order_id = ?", proposalID, order.ID).First(&proposal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusNotFound, "Meetup proposal not found")
+		}
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to get meetup proposal")
+	}
+
+	if proposal.Status != models.MeetupProposalOpen {
+		return nil, nil, fiber.NewError(fiber.StatusConflict, "Meetup proposal is no longer open")
+	}
+	if proposal.ProposedBy == actor {
+		return nil, nil, fiber.NewError(fiber.StatusForbidden, "You cannot answer your own proposal")
+	}
+
+	return order, &proposal, nil
+}
+
+// checkMeetupOpen makes sure the meetup of an order can still be arranged
+func checkMeetupOpen(order *models.Order) error {
+	if order.FulfilmentMethod != models.FulfilmentMeetup {
+		return fiber.NewError(fiber.StatusBadRequest, "Order is not a meetup order")
+	}
+	switch order.Status {
+	case models.OrderStatusPending, models.OrderStatusAwaitingHandoff, models.OrderStatusPaid, models.OrderStatusReady:
+		return nil
+	default:
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Meetup can't be changed once the order is %s", order.Status))
+	}
+}
+
+// findMeetupSpot loads a meetup spot by ID
+func findMeetupSpot(id string) (*models.MeetupSpot, error) {
+	spotID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid meetup spot ID")
+	}
+
+	var spot models.MeetupSpot
+	if err := database.DB.First(&spot, "id = ?", spotID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Meetup spot not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to get meetup spot")
+	}
+
+	return &spot, nil
+}
+
+// SendMeetupReminders emails both parties of paid or cash meetup orders
+// that are about to meet. Each meetup is reminded once.
+func SendMeetupReminders(config *configs.Config) error {
+	now := time.Now()
+
+	var orders []models.Order
+	if err := database.DB.Preload("User").Preload("Seller").Preload("MeetupSpot").Preload("Items").
+		Where("fulfilment_method = ? AND status IN ? AND meetup_reminded_at IS NULL AND meetup_at BETWEEN ? AND ?",
+			models.FulfilmentMeetup,
+			[]models.OrderStatus{models.OrderStatusAwaitingHandoff, models.OrderStatusPaid, models.OrderStatusReady},
+			now, now.Add(config.MeetupReminderLead)).
+		Find(&orders).Error; err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.MeetupSpot == nil || order.MeetupAt == nil {
+			continue
+		}
+
+		titles := make([]string, len(order.Items))
+		for i, item := range order.Items {
+			titles[i] = item.Title
+		}
+		itemTitle := strings.Join(titles, ", ")
+
+		for _, to := range []string{order.User.Email, order.Seller.Email} {
+			if err := utils.SendMeetupReminderEmail(to, itemTitle, order.MeetupSpot.Name, *order.MeetupAt, config); err != nil {
+				log.Printf("Error sending meetup reminder for order %s to %s: %v", order.ID, to, err)
+			}
+		}
+
+		// Don't retry failed emails every minute until the meetup
+		if err := database.DB.Model(&order).Update("meetup_reminded_at", now).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func meetupSpotToResponse(spot *models.MeetupSpot) types.MeetupSpotResponse {
+	return types.MeetupSpotResponse{
+		ID:          spot.ID,
+		Name:        spot.Name,
+		Description: spot.Description,
+		IsActive:    spot.IsActive,
+	}
+}
+
+// meetupToResponse returns the meetup arrangements of an order, or nil if it
+// isn't a meetup order
+func meetupToResponse(order *models.Order) *types.MeetupResponse {
+	if order.FulfilmentMethod != models.FulfilmentMeetup {
+		return nil
+	}
+
+	response := &types.MeetupResponse{
+		StartsAt:  order.MeetupAt,
+		Proposals: make([]types.MeetupProposalResponse, len(order.MeetupProposals)),
+	}
+	if order.MeetupSpot != nil {
+		spot := meetupSpotToResponse(order.MeetupSpot)
+		response.Spot = &spot
+	}
+	for i, proposal := range order.MeetupProposals {
+		response.Proposals[i] = types.MeetupProposalResponse{
+			ID:           proposal.ID,
+			Spot:         meetupSpotToResponse(&proposal.Spot),
+			StartsAt:     proposal.StartsAt,
+			ProposedByID: proposal.ProposedByID,
+			ProposedBy:   string(proposal.ProposedBy),
+			Status:       string(proposal.Status),
+			CreatedAt:    proposal.CreatedAt,
+		}
+	}
+
+	return response
+}
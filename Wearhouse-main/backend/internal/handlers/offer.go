@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OfferHandler struct {
+	config *configs.Config
+}
+
+func NewOfferHandler(config *configs.Config) *OfferHandler {
+	return &OfferHandler{
+		config: config,
+	}
+}
+
+// CreateOffer lets a buyer propose a price on a SALE listing
+func (h *OfferHandler) CreateOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CreateOfferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	var product models.Product
+	if err := database.DB.First(&product, "id = ?", req.ProductID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Product not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get product")
+	}
+
+	if product.ListingType != models.Sale {
+		return fiber.NewError(fiber.StatusBadRequest, "Offers can only be made on listings for sale")
+	}
+	if product.UserID == claims.UserID {
+		return fiber.NewError(fiber.StatusBadRequest, "You cannot make an offer on your own listing")
+	}
+	if !product.IsAvailable {
+		return fiber.NewError(fiber.StatusBadRequest, "Product is not available")
+	}
+	if product.IsReservedForOther(claims.UserID, time.Now()) {
+		return fiber.NewError(fiber.StatusConflict, "Product is reserved for another buyer")
+	}
+
+	// Only one open offer per buyer and listing
+	var open int64
+	if err := database.DB.Model(&models.Offer{}).
+		Where("product_id = ? AND buyer_id = ? AND status IN ?", product.ID, claims.UserID,
+			[]models.OfferStatus{models.OfferStatusPending, models.OfferStatusCountered}).
+		Count(&open).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to check existing offers")
+	}
+	if open > 0 {
+		return fiber.NewError(fiber.StatusConflict, "You already have an open offer on this product")
+	}
+
+	offer := models.Offer{
+		ProductID: product.ID,
+		BuyerID:   claims.UserID,
+		SellerID:  product.UserID,
+		Amount:    money.FromFloat(req.Amount),
+		Message:   req.Message,
+		Status:    models.OfferStatusPending,
+		ExpiresAt: time.Now().Add(h.config.OfferExpiry),
+	}
+	if err := database.DB.Create(&offer).Error; err != nil {
+		log.Printf("Error creating offer: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create offer")
+	}
+	offer.Product = product
+
+	return c.Status(fiber.StatusCreated).JSON(offerToResponse(&offer))
+}
+
+// GetOffers lists the offers the user made, or with ?role=seller the offers
+// received on their listings
+func (h *OfferHandler) GetOffers(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	query := database.DB.Preload("Product")
+	switch c.Query("role", "buyer") {
+	case "buyer":
+		query = query.Where("buyer_id = ?", claims.UserID)
+	case "seller":
+		query = query.Where("seller_id = ?", claims.UserID)
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid role")
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var offers []models.Offer
+	if err := query.Order("updated_at desc").Find(&offers).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get offers")
+	}
+
+	response := make([]types.OfferResponse, len(offers))
+	for i, offer := range offers {
+		response[i] = *offerToResponse(&offer)
+	}
+
+	return c.JSON(response)
+}
+
+// GetOffer returns a single offer visible to its buyer or seller
+func (h *OfferHandler) GetOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	offer, err := findOfferForParty(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(offerToResponse(offer))
+}
+
+// AcceptOffer accepts the current amount and reserves the listing for the
+// buyer by placing it in their cart at the agreed price
+func (h *OfferHandler) AcceptOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	offer, err := findOfferForParty(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkOfferTurn(offer, claims.UserID); err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the listing so two offers can't be accepted at once
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&product, "id = ?", offer.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Product not found")
+			}
+			return err
+		}
+		now := time.Now()
+		if !product.IsAvailable {
+			return fiber.NewError(fiber.StatusConflict, "Product is not available")
+		}
+		if product.IsReservedForOther(offer.BuyerID, now) {
+			return fiber.NewError(fiber.StatusConflict, "Product is reserved for another buyer")
+		}
+
+		reservedUntil := now.Add(h.config.OfferReservation)
+		if err := tx.Model(&product).Updates(map[string]interface{}{
+			"reserved_for_id": offer.BuyerID,
+			"reserved_until":  reservedUntil,
+		}).Error; err != nil {
+			return err
+		}
+
+		offer.Status = models.OfferStatusAccepted
+		offer.ExpiresAt = reservedUntil
+		if err := tx.Save(offer).Error; err != nil {
+			return err
+		}
+
+		return addOfferToCart(tx, offer)
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error accepting offer: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to accept offer")
+	}
+
+	return c.JSON(offerToResponse(offer))
+}
+
+// DeclineOffer ends the negotiation without a deal
+func (h *OfferHandler) DeclineOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	offer, err := findOfferForParty(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkOfferTurn(offer, claims.UserID); err != nil {
+		return err
+	}
+
+	offer.Status = models.OfferStatusDeclined
+	if err := database.DB.Save(offer).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to decline offer")
+	}
+
+	return c.JSON(offerToResponse(offer))
+}
+
+// CounterOffer proposes a different amount and hands the turn to the other party
+func (h *OfferHandler) CounterOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CounterOfferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	offer, err := findOfferForParty(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := checkOfferTurn(offer, claims.UserID); err != nil {
+		return err
+	}
+
+	if offer.Status == models.OfferStatusPending {
+		offer.Status = models.OfferStatusCountered
+	} else {
+		offer.Status = models.OfferStatusPending
+	}
+	offer.Amount = money.FromFloat(req.Amount)
+	offer.Message = req.Message
+	offer.ExpiresAt = time.Now().Add(h.config.OfferExpiry)
+	if err := database.DB.Save(offer).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to counter offer")
+	}
+
+	return c.JSON(offerToResponse(offer))
+}
+
+// WithdrawOffer lets the buyer back out of an open or accepted offer
+func (h *OfferHandler) WithdrawOffer(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	offer, err := findOfferForParty(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	if offer.BuyerID != claims.UserID {
+		return fiber.NewError(fiber.StatusForbidden, "Only the buyer can withdraw an offer")
+	}
+	if !offer.IsOpen() && offer.Status != models.OfferStatusAccepted {
+		return fiber.NewError(fiber.StatusConflict, "Offer can no longer be withdrawn")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if offer.Status == models.OfferStatusAccepted {
+			if err := releaseOffer(tx, offer); err != nil {
+				return err
+			}
+		}
+		offer.Status = models.OfferStatusWithdrawn
+		return tx.Save(offer).Error
+	})
+	if err != nil {
+		log.Printf("Error withdrawing offer: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to withdraw offer")
+	}
+
+	return c.JSON(offerToResponse(offer))
+}
+
+// ExpireOffers closes open offers nobody answered in time and releases
+// listings held by accepted offers the buyer never checked out
+func ExpireOffers() error {
+	now := time.Now()
+
+	if err := database.DB.Model(&models.Offer{}).
+		Where("status IN ? AND expires_at < ?",
+			[]models.OfferStatus{models.OfferStatusPending, models.OfferStatusCountered}, now).
+		Update("status", models.OfferStatusExpired).Error; err != nil {
+		return fmt.Errorf("failed to expire open offers: %w", err)
+	}
+
+	var lapsed []models.Offer
+	if err := database.DB.Where("status = ? AND expires_at < ?", models.OfferStatusAccepted, now).
+		Find(&lapsed).Error; err != nil {
+		return fmt.Errorf("failed to find lapsed offers: %w", err)
+	}
+	for i := range lapsed {
+		offer := &lapsed[i]
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := releaseOffer(tx, offer); err != nil {
+				return err
+			}
+			offer.Status = models.OfferStatusExpired
+			return tx.Save(offer).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to release offer %s: %w", offer.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// findOfferForParty loads an offer if userID is its buyer or seller
+func findOfferForParty(id string, userID uuid.UUID) (*models.Offer, error) {
+	offerID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid offer ID")
+	}
+
+	var offer models.Offer
+	if err := database.DB.Preload("Product").
+		Where("id = ? AND (buyer_id = ? OR seller_id = ?)", offerID, userID, userID).
+		First(&offer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Offer not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to get offer")
+	}
+
+	return &offer, nil
+}
+
+// checkOfferTurn makes sure the offer is open and waiting on userID: the
+// seller answers pending offers and the buyer answers counter-offers
+func checkOfferTurn(offer *models.Offer, userID uuid.UUID) error {
+	if !offer.IsOpen() || time.Now().After(offer.ExpiresAt) {
+		return fiber.NewError(fiber.StatusConflict, "Offer is no longer open")
+	}
+
+	waitingOn := offer.SellerID
+	if offer.Status == models.OfferStatusCountered {
+		waitingOn = offer.BuyerID
+	}
+	if waitingOn != userID {
+		return fiber.NewError(fiber.StatusForbidden, "It is not your turn to respond to this offer")
+	}
+
+	return nil
+}
+
+// addOfferToCart places the offered product in the buyer's cart, linked to
+// the offer so checkout charges the agreed amount
+func addOfferToCart(tx *gorm.DB, offer *models.Offer) error {
+	var cart models.Cart
+	if err := tx.FirstOrCreate(&cart, models.Cart{UserID: offer.BuyerID}).Error; err != nil {
+		return err
+	}
+
+	var cartItem models.CartItem
+	err := tx.Where("cart_id = ? AND product_id = ?", cart.ID, offer.ProductID).First(&cartItem).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		cartItem = models.CartItem{
+			CartID:    cart.ID,
+			ProductID: offer.ProductID,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	cartItem.Quantity = 1
+	cartItem.OfferID = &offer.ID
+	cartItem.QuotedPrice = offer.Amount
+	return tx.Save(&cartItem).Error
+}
+
+// releaseOffer drops the reservation an accepted offer holds on its listing
+// and removes the discounted item from the buyer's cart
+func releaseOffer(tx *gorm.DB, offer *models.Offer) error {
+	if err := tx.Model(&models.Product{}).
+		Where("id = ? AND reserved_for_id = ?", offer.ProductID, offer.BuyerID).
+		Updates(map[string]interface{}{
+			"reserved_for_id": nil,
+			"reserved_until":  nil,
+		}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("offer_id = ?", offer.ID).Delete(&models.CartItem{}).Error
+}
+
+// Helper function to convert Offer model to OfferResponse
+func offerToResponse(offer *models.Offer) *types.OfferResponse {
+	return &types.OfferResponse{
+		ID:        offer.ID,
+		ProductID: offer.ProductID,
+		Product:   productToResponse(&offer.Product),
+		BuyerID:   offer.BuyerID,
+		SellerID:  offer.SellerID,
+		Amount:    offer.Amount,
+		Message:   offer.Message,
+		Status:    string(offer.Status),
+		ExpiresAt: offer.ExpiresAt,
+		CreatedAt: offer.CreatedAt,
+		UpdatedAt: offer.UpdatedAt,
+	}
+}
@@ -2,18 +2,53 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wearhouse/configs"
 	"wearhouse/internal/database"
 	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/shipping"
 	"wearhouse/internal/types"
 	"wearhouse/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CreateOrder converts the user's cart to an order
-func CreateOrder(c *fiber.Ctx) error {
+type OrderHandler struct {
+	config   *configs.Config
+	payments *PaymentHandler
+	carrier  shipping.Carrier
+}
+
+func NewOrderHandler(config *configs.Config, payments *PaymentHandler) *OrderHandler {
+	return &OrderHandler{
+		config:   config,
+		payments: payments,
+		carrier:  shipping.New(config),
+	}
+}
+
+// CreateCheckout converts the user's cart into a checkout with one order per
+// seller, paid for together
+func (h *OrderHandler) CreateCheckout(c *fiber.Ctx) error {
+	return h.createCheckout(c, false)
+}
+
+// CreateOrder converts the user's cart into an order. It predates checkouts
+// and answers with the order alone, so it only takes carts from a single
+// seller; others go through CreateCheckout.
+func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
+	return h.createCheckout(c, true)
+}
+
+// createCheckout places the user's cart as a checkout. With singleOrder the
+// cart must hold a single seller's items and the response is their order.
+func (h *OrderHandler) createCheckout(c *fiber.Ctx, singleOrder bool) error {
 	// Get user from context (set by auth middleware)
 	claims := c.Locals("user").(*utils.JWTClaims)
 	user := &models.User{ID: claims.UserID}
@@ -25,25 +60,52 @@ func CreateOrder(c *fiber.Ctx) error {
 	}
 
 	// Validate request
-	if req.ShippingAddr == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "Shipping address is required")
+	fulfilment := models.FulfilmentMethod(req.FulfilmentMethod)
+	if fulfilment == "" {
+		fulfilment = models.FulfilmentShip
 	}
-	if req.BillingAddr == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "Billing address is required")
+	if fulfilment != models.FulfilmentShip && fulfilment != models.FulfilmentMeetup {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid fulfilment method")
+	}
+	if fulfilment == models.FulfilmentShip && req.ShippingAddressID == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Shipping address is required")
 	}
 	if req.PaymentMethod == "" {
 		return fiber.NewError(fiber.StatusBadRequest, "Payment method is required")
 	}
-	if req.PaymentMethod != "credit_card" && req.PaymentMethod != "paypal" {
+	switch req.PaymentMethod {
+	case models.PaymentMethodCreditCard, models.PaymentMethodPayPal:
+	case models.PaymentMethodCashOnMeetup:
+		if fulfilment != models.FulfilmentMeetup {
+			return fiber.NewError(fiber.StatusBadRequest, "Cash can only be paid at a meetup")
+		}
+	default:
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid payment method")
 	}
+	cash := req.PaymentMethod == models.PaymentMethodCashOnMeetup
+	if cash && req.UseCredit {
+		return fiber.NewError(fiber.StatusBadRequest, "Store credit can't be used when paying in cash")
+	}
 
-	// Get user's cart
-	var cart models.Cart
-	if err := database.DB.Preload("Items.Product").Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusNotFound, "Cart not found")
+	// Look up the addresses in the buyer's address book. Meetups aren't
+	// shipped anywhere.
+	var shippingAddr, billingAddr *models.Address
+	var err error
+	if fulfilment == models.FulfilmentShip {
+		if shippingAddr, err = findAddress(req.ShippingAddressID.String(), user.ID); err != nil {
+			return err
+		}
+		billingAddr = shippingAddr
+	}
+	if req.BillingAddressID != nil {
+		if billingAddr, err = findAddress(req.BillingAddressID.String(), user.ID); err != nil {
+			return err
 		}
+	}
+
+	// Get user's cart
+	cart, err := loadCart(user.ID)
+	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get cart")
 	}
 
@@ -52,10 +114,83 @@ func CreateOrder(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Cart is empty")
 	}
 
-	// Calculate cart total
-	var total float64
+	// Refuse to check out until the buyer has acknowledged every change to
+	// the listings since they were added (see ValidateCart)
+	if changes := validateCartItems(cart.Items, user.ID); len(changes) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(types.CartValidationResponse{
+			Valid:   false,
+			Changes: changes,
+			Cart:    *cartToResponse(cart),
+		})
+	}
+
+	// Every listing is a single unique item. Anything else, e.g. a quantity
+	// of 0 or less saved before quantities were validated, would change
+	// what the other items cost.
+	for _, item := range cart.Items {
+		if item.Quantity != 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "Only one of "+item.Product.Title+" is available, set its quantity to 1")
+		}
+		if cash && !item.Product.AcceptsCash {
+			return fiber.NewError(fiber.StatusBadRequest, "The seller of "+item.Product.Title+" doesn't accept cash")
+		}
+	}
+
+	// Work out what the cart's coupon takes off. We pay for the discount,
+	// which we can't do on cash paid to the seller.
+	var discounts map[uuid.UUID]money.Money
+	discount := money.Cents(0)
+	if cart.Coupon != nil {
+		if cash {
+			return fiber.NewError(fiber.StatusBadRequest, "Coupons can't be used when paying in cash")
+		}
+		if discounts, err = couponDiscounts(database.DB, cart.Coupon, user.ID, cart.Items, time.Now()); err != nil {
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				return err
+			}
+			log.Printf("Error checking coupon %s: %v", cart.Coupon.Code, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to check coupon")
+		}
+		for _, amount := range discounts {
+			discount = discount.Add(amount)
+		}
+	}
+
+	// Group the items by seller, keeping the cart order
+	var sellerIDs []uuid.UUID
+	itemsBySeller := make(map[uuid.UUID][]models.CartItem)
 	for _, item := range cart.Items {
-		total += item.Product.Price * float64(item.Quantity)
+		sellerID := item.Product.UserID
+		if _, ok := itemsBySeller[sellerID]; !ok {
+			sellerIDs = append(sellerIDs, sellerID)
+		}
+		itemsBySeller[sellerID] = append(itemsBySeller[sellerID], item)
+	}
+	if singleOrder && len(sellerIDs) > 1 {
+		return fiber.NewError(fiber.StatusConflict, "The cart has items from several sellers, check out with POST /api/checkouts")
+	}
+
+	// Sales tax is charged where the buyer gets the goods: at the shipping
+	// address, or on campus for meetups
+	province := h.config.CampusProvince
+	if shippingAddr != nil {
+		province = shippingAddr.PostalAddress.Province
+	}
+
+	// Cash is paid to the seller directly, so there is no fee or tax we
+	// could collect
+	var schedule *models.FeeSchedule
+	var rates []models.TaxRate
+	if !cash {
+		if schedule, err = currentFeeSchedule(database.DB); err != nil {
+			log.Printf("Error getting fee schedule: %v", err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get fees")
+		}
+		if rates, err = currentTaxRates(database.DB, province); err != nil {
+			log.Printf("Error getting tax rates for %s: %v", province, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get tax rates")
+		}
 	}
 
 	// Start transaction
@@ -64,32 +199,207 @@ func CreateOrder(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start transaction")
 	}
 
-	// Create order
-	order := models.Order{
+	// Hold every product for this buyer until the checkout is paid. Cash
+	// orders are paid at the meetup, so they hold them until then.
+	status := models.OrderStatusPending
+	reason := "Order placed"
+	var reservedUntil *time.Time
+	if cash {
+		status = models.OrderStatusAwaitingHandoff
+		reason = "Order placed, to be paid in cash at the meetup"
+	} else {
+		until := time.Now().Add(h.config.PaymentReservation)
+		reservedUntil = &until
+	}
+	if err := reserveProducts(tx, user.ID, cart.Items, reservedUntil); err != nil {
+		tx.Rollback()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error reserving products: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to reserve products")
+	}
+
+	// Create checkout
+	checkout := models.Checkout{
 		UserID:        user.ID,
-		Status:        models.OrderStatusPending,
-		Total:         total,
-		ShippingAddr:  req.ShippingAddr,
-		BillingAddr:   req.BillingAddr,
+		Status:        models.CheckoutStatusPending,
+		Total:         money.Cents(0),
 		PaymentMethod: req.PaymentMethod,
 	}
-
-	if err := tx.Create(&order).Error; err != nil {
+	if err := tx.Create(&checkout).Error; err != nil {
 		tx.Rollback()
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create order")
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create checkout")
+	}
+	if discount.Cents > 0 {
+		if err := redeemCoupon(tx, cart.Coupon.ID, user.ID, checkout.ID, discount); err != nil {
+			tx.Rollback()
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				return err
+			}
+			log.Printf("Error redeeming coupon %s: %v", cart.Coupon.Code, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to redeem coupon")
+		}
 	}
 
-	// Create order items
-	for _, cartItem := range cart.Items {
-		orderItem := models.OrderItem{
+	// Store credit pays for the orders one after the other, as far as it
+	// goes. The buyer is locked so the same credit can't go to two checkouts.
+	var credit, creditUsed, amountDue int64
+	if req.UseCredit {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, "id = ?", user.ID).Error; err != nil {
+			tx.Rollback()
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get user")
+		}
+		balance, held, err := walletCredit(tx, user.ID)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error getting store credit for user %s: %v", user.ID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get store credit")
+		}
+		credit = max(balance-held, 0)
+	}
+
+	// Create one order per seller
+	for _, sellerID := range sellerIDs {
+		items := itemsBySeller[sellerID]
+
+		// Price every item first, the order is for their total less the
+		// coupon's discount, fees and taxes
+		orderItems := make([]models.OrderItem, len(items))
+		var taxLines []models.TaxLine
+		total, fees, taxes := money.Cents(0), money.Cents(0), money.Cents(0)
+		orderDiscount := money.Cents(0)
+		for i, cartItem := range items {
+			orderItem := models.OrderItem{
+				ID:       uuid.New(), // Tax lines point at it
+				Quantity: cartItem.Quantity,
+				Price:    cartItem.UnitPrice(),
+				Discount: discounts[cartItem.ID],
+			}
+			orderItem.SnapshotProduct(&cartItem.Product)
+			if cartItem.Offer != nil && cartItem.Offer.Status == models.OfferStatusAccepted {
+				orderItem.OfferID = cartItem.OfferID
+			}
+			if schedule != nil {
+				rule, fee := schedule.FeeFor(models.FeeItem{
+					ListingType: cartItem.Product.ListingType,
+					Category:    cartItem.Product.Category,
+					SellerTier:  cartItem.Product.User.SellerTier,
+					Price:       orderItem.Price,
+				})
+				if rule != nil {
+					orderItem.FeeRuleID = &rule.ID
+					orderItem.Fee = fee.Mul(orderItem.Quantity)
+				}
+			}
+			for _, line := range taxOrderItem(rates, province, &orderItem, fulfilment == models.FulfilmentShip) {
+				taxLines = append(taxLines, line)
+				taxes = taxes.Add(line.Amount)
+			}
+			orderItems[i] = orderItem
+			total = total.Add(orderItem.Price.Mul(orderItem.Quantity))
+			orderDiscount = orderDiscount.Add(orderItem.Discount)
+			fees = fees.Add(orderItem.Fee)
+		}
+		total = total.Add(fees).Add(taxes).Sub(orderDiscount)
+
+		order := models.Order{
+			CheckoutID:       checkout.ID,
+			UserID:           user.ID,
+			SellerID:         sellerID,
+			Status:           status,
+			FulfilmentMethod: fulfilment,
+			Total:            total,
+			Discount:         orderDiscount,
+			Fee:              fees,
+			Tax:              taxes,
+			Credit:           money.Cents(min(credit, total.Cents)),
+			PaymentMethod:    req.PaymentMethod,
+			ReservedUntil:    reservedUntil,
+		}
+		if schedule != nil {
+			order.FeeScheduleID = &schedule.ID
+		}
+		if orderDiscount.Cents > 0 {
+			order.CouponID = &cart.Coupon.ID
+		}
+		credit -= order.Credit.Cents
+		creditUsed += order.Credit.Cents
+		amountDue += total.Cents - order.Credit.Cents
+		if shippingAddr != nil {
+			order.ShippingAddrID = &shippingAddr.ID
+			order.ShippingAddress = shippingAddr.PostalAddress
+			order.ShippingAddr = shippingAddr.PostalAddress.String()
+		}
+		if billingAddr != nil {
+			order.BillingAddrID = &billingAddr.ID
+			order.BillingAddress = billingAddr.PostalAddress
+			order.BillingAddr = billingAddr.PostalAddress.String()
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			tx.Rollback()
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to create order")
+		}
+		if err := tx.Create(&models.OrderStatusHistory{
 			OrderID:   order.ID,
-			ProductID: cartItem.ProductID,
-			Quantity:  cartItem.Quantity,
-			Price:     cartItem.Product.Price,
+			ToStatus:  status,
+			ActorID:   &user.ID,
+			ActorRole: models.ActorBuyer,
+			Reason:    reason,
+		}).Error; err != nil {
+			tx.Rollback()
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to record order status")
+		}
+		checkout.Total = checkout.Total.Add(total)
+
+		// Create order items
+		for _, orderItem := range orderItems {
+			orderItem.OrderID = order.ID
+			if err := tx.Create(&orderItem).Error; err != nil {
+				tx.Rollback()
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to create order items")
+			}
+
+			// The negotiated price has been used
+			if orderItem.OfferID != nil {
+				if err := tx.Model(&models.Offer{}).Where("id = ?", *orderItem.OfferID).
+					Update("status", models.OfferStatusCompleted).Error; err != nil {
+					tx.Rollback()
+					return fiber.NewError(fiber.StatusInternalServerError, "Failed to update offer")
+				}
+			}
+		}
+
+		for i := range taxLines {
+			taxLines[i].OrderID = order.ID
+		}
+		if len(taxLines) > 0 {
+			if err := tx.Create(&taxLines).Error; err != nil {
+				tx.Rollback()
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to record taxes")
+			}
+		}
+	}
+
+	// The buyer pays the sum of the seller orders
+	if err := tx.Model(&checkout).Updates(models.Checkout{Total: checkout.Total}).Error; err != nil {
+		tx.Rollback()
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update checkout")
+	}
+
+	// Nothing is charged online when store credit, a coupon or free listings
+	// cover everything, so the checkout is paid there and then
+	if !cash && amountDue == 0 {
+		reason := "Nothing to pay"
+		if creditUsed > 0 {
+			reason = "Paid with store credit"
 		}
-		if err := tx.Create(&orderItem).Error; err != nil {
+		if err := settleCheckout(tx, checkout.ID, reason); err != nil {
 			tx.Rollback()
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to create order items")
+			log.Printf("Error settling checkout %s: %v", checkout.ID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to settle checkout")
 		}
 	}
 
@@ -98,28 +408,57 @@ func CreateOrder(c *fiber.Ctx) error {
 		tx.Rollback()
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to clear cart items")
 	}
+	if err := tx.Model(cart).Update("coupon_id", nil).Error; err != nil {
+		tx.Rollback()
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to clear cart coupon")
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to commit transaction")
 	}
 
-	// Load order with items and products for response
-	if err := database.DB.Preload("Items.Product").First(&order, order.ID).Error; err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load order")
+	// Load checkout with orders, items and products for response
+	if err := database.DB.Scopes(withCheckoutDetails).First(&checkout, "id = ?", checkout.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load checkout")
 	}
 
 	// Convert to response type
-	return c.Status(fiber.StatusCreated).JSON(orderToResponse(&order))
+	if singleOrder {
+		return c.Status(fiber.StatusCreated).JSON(orderToResponse(&checkout.Orders[0]))
+	}
+	return c.Status(fiber.StatusCreated).JSON(checkoutToResponse(&checkout))
+}
+
+// GetCheckout returns a checkout and its per-seller orders
+func (h *OrderHandler) GetCheckout(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	checkoutID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid checkout ID")
+	}
+
+	var checkout models.Checkout
+	if err := database.DB.Scopes(withCheckoutDetails).
+		Where("id = ? AND user_id = ?", checkoutID, claims.UserID).First(&checkout).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, "Checkout not found")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get checkout")
+	}
+
+	return c.JSON(checkoutToResponse(&checkout))
 }
 
 // GetOrders returns all orders for the authenticated user
-func GetOrders(c *fiber.Ctx) error {
+func (h *OrderHandler) GetOrders(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*utils.JWTClaims)
 	user := &models.User{ID: claims.UserID}
 
 	var orders []models.Order
-	if err := database.DB.Preload("Items.Product").Where("user_id = ?", user.ID).Find(&orders).Error; err != nil {
+	if err := database.DB.Scopes(withOrderDetails).
+		Where("user_id = ?", user.ID).Find(&orders).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get orders")
 	}
 
@@ -133,7 +472,7 @@ func GetOrders(c *fiber.Ctx) error {
 }
 
 // GetOrder returns a specific order by ID
-func GetOrder(c *fiber.Ctx) error {
+func (h *OrderHandler) GetOrder(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*utils.JWTClaims)
 	user := &models.User{ID: claims.UserID}
 
@@ -143,7 +482,8 @@ func GetOrder(c *fiber.Ctx) error {
 	}
 
 	var order models.Order
-	if err := database.DB.Preload("Items.Product").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+	if err := database.DB.Scopes(withOrderDetails).
+		Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Order not found")
 		}
@@ -153,8 +493,9 @@ func GetOrder(c *fiber.Ctx) error {
 	return c.JSON(orderToResponse(&order))
 }
 
-// UpdateOrderStatus updates the status of an order
-func UpdateOrderStatus(c *fiber.Ctx) error {
+// UpdateOrderStatus moves an order to a new status. The buyer and the seller
+// may each only make the moves the order state machine grants their role.
+func (h *OrderHandler) UpdateOrderStatus(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*utils.JWTClaims)
 	user := &models.User{ID: claims.UserID}
 
@@ -169,65 +510,532 @@ func UpdateOrderStatus(c *fiber.Ctx) error {
 	}
 
 	// Validate status
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"paid":      true,
-		"shipped":   true,
-		"delivered": true,
-		"cancelled": true,
-	}
-	if !validStatuses[req.Status] {
+	if err := validate.Struct(req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid order status")
 	}
 
+	order, actor, err := findOrderForParty(orderID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// Update status
+	to := models.OrderStatus(req.Status)
+	if to == models.OrderStatusCancelled {
+		err = h.cancelOrder(order, actor, &user.ID, req.Reason, false)
+	} else {
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			return transitionOrder(tx, order, to, actor, &user.ID, req.Reason)
+		})
+	}
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update order status")
+	}
+
+	// Load order with items and products for response
+	if err := database.DB.Scopes(withOrderDetails).
+		First(order, "id = ?", order.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load order")
+	}
+
+	return c.JSON(orderToResponse(order))
+}
+
+// CancelOrder lets the buyer cancel an order before it ships. Paid orders
+// are refunded, to the original payment method or, with "refund_to":
+// "credit", to the buyer's wallet.
+func (h *OrderHandler) CancelOrder(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var req types.CancelOrderRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
 	var order models.Order
-	if err := database.DB.Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+	if err := database.DB.Where("id = ? AND user_id = ?", orderID, claims.UserID).First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusNotFound, "Order not found")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get order")
 	}
 
-	// Update status
-	order.Status = models.OrderStatus(req.Status)
-	if err := database.DB.Save(&order).Error; err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update order status")
+	if err := h.cancelOrder(&order, models.ActorBuyer, &claims.UserID, req.Reason, req.RefundTo == "credit"); err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error cancelling order %s: %v", order.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to cancel order")
 	}
 
-	// Load order with items and products for response
-	if err := database.DB.Preload("Items.Product").First(&order, order.ID).Error; err != nil {
+	if err := database.DB.Scopes(withOrderDetails).First(&order, "id = ?", order.ID).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load order")
 	}
 
 	return c.JSON(orderToResponse(&order))
 }
 
+// findOrderForParty loads an order that userID bought or sold, along with
+// the role they play in it
+func findOrderForParty(orderID, userID uuid.UUID) (*models.Order, models.OrderActor, error) {
+	var order models.Order
+	if err := database.DB.Where("id = ? AND (user_id = ? OR seller_id = ?)", orderID, userID, userID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		return nil, "", fiber.NewError(fiber.StatusInternalServerError, "Failed to get order")
+	}
+
+	actor := models.ActorBuyer
+	if order.SellerID == userID {
+		actor = models.ActorSeller
+	}
+
+	return &order, actor, nil
+}
+
+// cancelOrder cancels an order on behalf of actor. Unpaid orders just give
+// their products back; paid ones are refunded first, or credited to the
+// buyer's wallet if refundAsCredit is set.
+func (h *OrderHandler) cancelOrder(order *models.Order, actor models.OrderActor, actorID *uuid.UUID, reason string, refundAsCredit bool) error {
+	if order.Status == models.OrderStatusPending || order.Status == models.OrderStatusAwaitingHandoff {
+		return database.DB.Transaction(func(tx *gorm.DB) error {
+			return releaseOrder(tx, order, actor, actorID, reason)
+		})
+	}
+
+	// Check before any money moves
+	if err := order.Status.CheckTransition(models.OrderStatusCancelled, actor); err != nil {
+		return transitionError(order.Status, models.OrderStatusCancelled, actor, err)
+	}
+
+	if refundAsCredit {
+		order.RefundAsCredit = true
+	} else if _, err := h.payments.RefundOrder(order, actor, reason); err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := restockOrder(tx, order); err != nil {
+			return err
+		}
+		if order.RefundAsCredit {
+			if err := tx.Model(order).Update("refund_as_credit", true).Error; err != nil {
+				return err
+			}
+		}
+		return transitionOrder(tx, order, models.OrderStatusCancelled, actor, actorID, reason)
+	})
+}
+
+// transitionOrder moves an order to a new status if actor may make that move
+// and records the change in the order's status history. Delivering an order
+// releases its escrow to the seller and cancelling one reverses its sale.
+func transitionOrder(tx *gorm.DB, order *models.Order, to models.OrderStatus, actor models.OrderActor, actorID *uuid.UUID, reason string) error {
+	if err := order.Status.CheckTransition(to, actor); err != nil {
+		return transitionError(order.Status, to, actor, err)
+	}
+	if to == models.OrderStatusShipped && order.FulfilmentMethod != models.FulfilmentShip {
+		return fiber.NewError(fiber.StatusConflict, "Meetup orders are handed over, not shipped")
+	}
+	if to == models.OrderStatusReady && order.FulfilmentMethod != models.FulfilmentMeetup {
+		return fiber.NewError(fiber.StatusConflict, "Only meetup orders can be ready for pickup")
+	}
+
+	from := order.Status
+	updates := map[string]interface{}{"status": to}
+	if from == models.OrderStatusPending {
+		// Payment is settled one way or the other
+		updates["reserved_until"] = nil
+	}
+	if err := tx.Model(order).Updates(updates).Error; err != nil {
+		return err
+	}
+	order.Status = to
+
+	if err := tx.Create(&models.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actorID,
+		ActorRole:  actor,
+		Reason:     reason,
+	}).Error; err != nil {
+		return err
+	}
+
+	// The money held for the seller follows the order
+	switch to {
+	case models.OrderStatusDelivered:
+		return releaseEscrow(tx, order)
+	case models.OrderStatusCancelled:
+		return reverseSale(tx, order)
+	}
+	return nil
+}
+
+// transitionError turns a rejected status transition into an HTTP error
+func transitionError(from, to models.OrderStatus, actor models.OrderActor, err error) error {
+	msg := fmt.Sprintf("Order cannot move from %s to %s", from, to)
+	if errors.Is(err, models.ErrTransitionForbidden) {
+		return fiber.NewError(fiber.StatusForbidden, msg+" as the "+string(actor))
+	}
+	return fiber.NewError(fiber.StatusConflict, msg)
+}
+
+// withOrderDetails preloads everything orderToResponse renders
+func withOrderDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items").
+		Preload("Items.FeeRule").
+		Preload("TaxLines").
+		Preload("Coupon").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		Preload("Refunds").
+		Preload("Shipment").
+		Preload("MeetupSpot").
+		Preload("MeetupProposals", func(db *gorm.DB) *gorm.DB { return db.Preload("Spot").Order("created_at asc") })
+}
+
+// withCheckoutDetails preloads everything checkoutToResponse renders
+func withCheckoutDetails(db *gorm.DB) *gorm.DB {
+	return db.Preload("Orders", withOrderDetails)
+}
+
+// reserveProducts locks the products behind the cart items and takes them off
+// the market for userID until reservedUntil, or until the order is settled
+// when it is nil. It fails if another buyer got there first.
+func reserveProducts(tx *gorm.DB, userID uuid.UUID, items []models.CartItem, reservedUntil *time.Time) error {
+	productIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+
+	// Lock in a stable order so concurrent checkouts can't deadlock
+	var products []models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", productIDs).Order("id").Find(&products).Error; err != nil {
+		return err
+	}
+	if len(products) != len(productIDs) {
+		return fiber.NewError(fiber.StatusConflict, "Some products in your cart are no longer listed")
+	}
+
+	now := time.Now()
+	for _, product := range products {
+		if !product.IsAvailable {
+			return fiber.NewError(fiber.StatusConflict, "Product "+product.Title+" is no longer available")
+		}
+		if product.IsReservedForOther(userID, now) {
+			return fiber.NewError(fiber.StatusConflict, "Product "+product.Title+" is reserved for another buyer")
+		}
+	}
+
+	return tx.Model(&models.Product{}).Where("id IN ?", productIDs).Updates(map[string]interface{}{
+		"is_available":    false,
+		"reserved_for_id": userID,
+		"reserved_until":  reservedUntil,
+	}).Error
+}
+
+// restockOrder puts the products of an order that won't go through back on
+// the market
+func restockOrder(tx *gorm.DB, order *models.Order) error {
+	productIDs := tx.Model(&models.OrderItem{}).Select("product_id").Where("order_id = ?", order.ID)
+	return tx.Model(&models.Product{}).
+		Where("id IN (?) AND reserved_for_id = ?", productIDs, order.UserID).
+		Updates(map[string]interface{}{
+			"is_available":    true,
+			"reserved_for_id": nil,
+			"reserved_until":  nil,
+		}).Error
+}
+
+// releaseOrder cancels an unpaid order and puts its products back on the market
+func releaseOrder(tx *gorm.DB, order *models.Order, actor models.OrderActor, actorID *uuid.UUID, reason string) error {
+	if err := restockOrder(tx, order); err != nil {
+		return err
+	}
+
+	if err := transitionOrder(tx, order, models.OrderStatusCancelled, actor, actorID, reason); err != nil {
+		return err
+	}
+
+	// The checkout is over once none of its orders can still be paid
+	open, err := countUnpaidOrders(tx, order.CheckoutID)
+	if err != nil {
+		return err
+	}
+	if open == 0 {
+		var paid int64
+		if err := tx.Model(&models.Order{}).
+			Where("checkout_id = ? AND status <> ?", order.CheckoutID, models.OrderStatusCancelled).
+			Count(&paid).Error; err != nil {
+			return err
+		}
+		status := models.CheckoutStatusCancelled
+		if paid > 0 {
+			// Some cash orders were already paid at their meetup
+			status = models.CheckoutStatusPaid
+		} else {
+			// Nothing was bought, so the coupon can be used again
+			if err := tx.Where("checkout_id = ?", order.CheckoutID).
+				Delete(&models.CouponRedemption{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Checkout{}).
+			Where("id = ? AND status = ?", order.CheckoutID, models.CheckoutStatusPending).
+			Update("status", status).Error
+	}
+	return nil
+}
+
+// countUnpaidOrders counts the orders of a checkout still waiting for payment,
+// online or in cash
+func countUnpaidOrders(tx *gorm.DB, checkoutID uuid.UUID) (int64, error) {
+	var open int64
+	err := tx.Model(&models.Order{}).
+		Where("checkout_id = ? AND status IN ?", checkoutID, []models.OrderStatus{
+			models.OrderStatusPending,
+			models.OrderStatusAwaitingHandoff,
+		}).
+		Count(&open).Error
+	return open, err
+}
+
+// releaseCheckout cancels every unpaid order of a checkout, e.g. when its
+// payment failed or timed out
+func releaseCheckout(tx *gorm.DB, checkoutID uuid.UUID, reason string) error {
+	var orders []models.Order
+	if err := tx.Where("checkout_id = ? AND status = ?", checkoutID, models.OrderStatusPending).
+		Find(&orders).Error; err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if err := releaseOrder(tx, &orders[i], models.ActorSystem, nil, reason); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settleCheckout marks a checkout and every unpaid seller order in it as
+// paid, and books the sales. The products are sold, so the reservation no
+// longer needs to expire.
+func settleCheckout(tx *gorm.DB, checkoutID uuid.UUID, reason string) error {
+	if err := tx.Model(&models.Checkout{}).Where("id = ?", checkoutID).
+		Update("status", models.CheckoutStatusPaid).Error; err != nil {
+		return err
+	}
+
+	orderIDs := tx.Model(&models.Order{}).Select("id").Where("checkout_id = ?", checkoutID)
+	productIDs := tx.Model(&models.OrderItem{}).Select("product_id").Where("order_id IN (?)", orderIDs)
+	if err := tx.Model(&models.Product{}).Where("id IN (?)", productIDs).
+		Update("reserved_until", nil).Error; err != nil {
+		return err
+	}
+
+	var orders []models.Order
+	if err := tx.Where("checkout_id = ? AND status = ?", checkoutID, models.OrderStatusPending).
+		Find(&orders).Error; err != nil {
+		return err
+	}
+	for i := range orders {
+		if err := transitionOrder(tx, &orders[i], models.OrderStatusPaid, models.ActorSystem, nil, reason); err != nil {
+			return err
+		}
+		if err := recordSale(tx, &orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseExpiredReservations cancels checkouts whose payment window has
+// passed and makes their products available again. Their payments are
+// cancelled with the provider first; a checkout whose payment might still go
+// through is left for the next run, or for the payment's webhook. It takes
+// the server's payment handler, whose providers know the payments it started.
+func ReleaseExpiredReservations(paymentHandler *PaymentHandler) error {
+	var checkoutIDs []uuid.UUID
+	if err := database.DB.Model(&models.Order{}).Distinct("checkout_id").
+		Where("status = ? AND reserved_until < ?", models.OrderStatusPending, time.Now()).
+		Pluck("checkout_id", &checkoutIDs).Error; err != nil {
+		return fmt.Errorf("failed to find expired reservations: %w", err)
+	}
+
+	for _, checkoutID := range checkoutIDs {
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Lock the checkout so no payment can be started for it meanwhile
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&models.Checkout{}, "id = ?", checkoutID).Error; err != nil {
+				return err
+			}
+			if err := paymentHandler.cancelPendingPayments(tx, checkoutID); err != nil {
+				return err
+			}
+			return releaseCheckout(tx, checkoutID, "Payment window expired")
+		}); err != nil {
+			log.Printf("Error releasing checkout %s: %v", checkoutID, err)
+		}
+	}
+
+	return nil
+}
+
 // Helper function to convert Order model to OrderResponse
 func orderToResponse(order *models.Order) *types.OrderResponse {
 	items := make([]types.OrderItemResponse, len(order.Items))
+	var fees []types.OrderFeeResponse
+	subtotal := money.Cents(0)
 	for i, item := range order.Items {
+		subtotal = subtotal.Add(item.Price.Mul(item.Quantity))
+		if item.Fee.Cents > 0 {
+			fee := types.OrderFeeResponse{OrderItemID: item.ID, Amount: item.Fee}
+			if item.FeeRule != nil {
+				fee.Name = item.FeeRule.Name
+			}
+			fees = append(fees, fee)
+		}
+
 		items[i] = types.OrderItemResponse{
-			ID:        item.ID,
-			ProductID: item.ProductID,
-			Product:   productToResponse(&item.Product),
-			Quantity:  item.Quantity,
-			Price:     item.Price,
-			CreatedAt: item.CreatedAt,
-			UpdatedAt: item.UpdatedAt,
+			ID:         item.ID,
+			ProductID:  item.ProductID,
+			Title:      item.Title,
+			Brand:      item.Brand,
+			Size:       item.Size,
+			Condition:  item.Condition,
+			ImageURL:   item.ImageURL,
+			SellerID:   item.SellerID,
+			SellerName: item.SellerName,
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			OfferID:    item.OfferID,
+			Fee:        item.Fee,
+			Discount:   item.Discount,
+			CreatedAt:  item.CreatedAt,
+			UpdatedAt:  item.UpdatedAt,
+		}
+	}
+
+	taxes := make([]types.OrderTaxResponse, len(order.TaxLines))
+	for i, line := range order.TaxLines {
+		taxes[i] = types.OrderTaxResponse{
+			OrderItemID: line.OrderItemID,
+			Base:        string(line.Base),
+			Name:        line.Name,
+			Province:    line.Province,
+			RatePercent: line.RatePercent(),
+			Taxable:     line.Taxable,
+			Amount:      line.Amount,
+		}
+	}
+
+	refunds := make([]types.RefundResponse, len(order.Refunds))
+	for i, refund := range order.Refunds {
+		refunds[i] = types.RefundResponse{
+			ID:          refund.ID,
+			PaymentID:   refund.PaymentID,
+			Amount:      refund.Amount,
+			Currency:    refund.Amount.Currency,
+			Status:      string(refund.Status),
+			Reason:      refund.Reason,
+			InitiatedBy: string(refund.InitiatedBy),
+			CreatedAt:   refund.CreatedAt,
 		}
 	}
 
+	history := make([]types.OrderStatusHistoryResponse, len(order.StatusHistory))
+	for i, entry := range order.StatusHistory {
+		history[i] = types.OrderStatusHistoryResponse{
+			FromStatus: string(entry.FromStatus),
+			ToStatus:   string(entry.ToStatus),
+			ActorID:    entry.ActorID,
+			ActorRole:  string(entry.ActorRole),
+			Reason:     entry.Reason,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+
+	var couponCode string
+	if order.Coupon != nil {
+		couponCode = order.Coupon.Code
+	}
+
 	return &types.OrderResponse{
-		ID:            order.ID,
-		UserID:        order.UserID,
-		Items:         items,
-		Status:        string(order.Status),
-		Total:         order.Total,
-		ShippingAddr:  order.ShippingAddr,
-		BillingAddr:   order.BillingAddr,
-		PaymentMethod: order.PaymentMethod,
-		CreatedAt:     order.CreatedAt,
-		UpdatedAt:     order.UpdatedAt,
+		ID:               order.ID,
+		CheckoutID:       order.CheckoutID,
+		UserID:           order.UserID,
+		SellerID:         order.SellerID,
+		Items:            items,
+		Status:           string(order.Status),
+		FulfilmentMethod: string(order.FulfilmentMethod),
+		Subtotal:         subtotal,
+		CouponCode:       couponCode,
+		Discount:         order.Discount,
+		Fees:             fees,
+		Fee:              order.Fee,
+		Taxes:            taxes,
+		Tax:              order.Tax,
+		Total:            order.Total,
+		Credit:           order.Credit,
+		RefundAsCredit:   order.RefundAsCredit,
+		ShippingAddress:  orderAddressToResponse(order.ShippingAddrID, order.ShippingAddress),
+		BillingAddress:   orderAddressToResponse(order.BillingAddrID, order.BillingAddress),
+		ShippingAddr:     order.ShippingAddr,
+		BillingAddr:      order.BillingAddr,
+		PaymentMethod:    order.PaymentMethod,
+		Meetup:           meetupToResponse(order),
+		Shipment:         shipmentToResponse(order.Shipment),
+		HandedOverAt:     order.HandedOverAt,
+		StatusHistory:    history,
+		Refunds:          refunds,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+	}
+}
+
+// Helper function to convert Checkout model to CheckoutResponse
+func checkoutToResponse(checkout *models.Checkout) *types.CheckoutResponse {
+	orders := make([]types.OrderResponse, len(checkout.Orders))
+	credit, due := money.Cents(0), money.Cents(0)
+	for i, order := range checkout.Orders {
+		orders[i] = *orderToResponse(&order)
+		credit = credit.Add(order.Credit)
+		if order.Status == models.OrderStatusPending {
+			due = due.Add(order.Total).Sub(order.Credit)
+		}
+	}
+
+	return &types.CheckoutResponse{
+		ID:            checkout.ID,
+		UserID:        checkout.UserID,
+		Orders:        orders,
+		Status:        string(checkout.Status),
+		Total:         checkout.Total,
+		Credit:        credit,
+		AmountDue:     due,
+		PaymentMethod: checkout.PaymentMethod,
+		CreatedAt:     checkout.CreatedAt,
+		UpdatedAt:     checkout.UpdatedAt,
 	}
 }
 
@@ -242,6 +1050,7 @@ func productToResponse(product *models.Product) types.ProductResponse {
 		Size:        product.Size,
 		Brand:       product.Brand,
 		Condition:   product.Condition,
+		ListingType: string(product.ListingType),
 		Price:       product.Price,
 		IsAvailable: product.IsAvailable,
 		Images:      product.Images,
@@ -249,3 +1058,13 @@ func productToResponse(product *models.Product) types.ProductResponse {
 		UpdatedAt:   product.UpdatedAt.String(),
 	}
 }
+
+// orderAddressToResponse returns the address snapshot of an order, or nil for
+// orders placed with a free-text address
+func orderAddressToResponse(addressID *uuid.UUID, address models.PostalAddress) *types.PostalAddressResponse {
+	if addressID == nil {
+		return nil
+	}
+	response := postalAddressToResponse(address)
+	return &response
+}
@@ -1,36 +1,54 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"time"
+	"wearhouse/configs"
 	"wearhouse/internal/database"
 	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/payments"
 	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/stripe/stripe-go/v76"
-	"github.com/stripe/stripe-go/v76/paymentintent"
-	"github.com/stripe/stripe-go/v76/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var validate = validator.New()
 
+func init() {
+	// Amounts are validated by their cents, e.g. min=0 refuses negative ones
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(money.Money).Cents
+	}, money.Money{})
+}
+
 type PaymentHandler struct {
-	stripeKey string
+	config    *configs.Config
+	providers map[string]payments.Provider // By payment method
 }
 
-func NewPaymentHandler(stripeKey string) *PaymentHandler {
-	stripe.Key = stripeKey
+func NewPaymentHandler(config *configs.Config) *PaymentHandler {
 	return &PaymentHandler{
-		stripeKey: stripeKey,
+		config:    config,
+		providers: payments.New(config),
 	}
 }
 
-// CreatePaymentIntent creates a new payment intent with Stripe
+// CreatePaymentIntent creates a payment intent for one of the
+// caller's pending checkouts. The amount is always computed from the orders,
+// and an intent that is still waiting for payment is handed out again rather
+// than creating a second one for the same checkout.
 func (h *PaymentHandler) CreatePaymentIntent(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
 	var req types.CreatePaymentIntentRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
@@ -41,130 +59,502 @@ func (h *PaymentHandler) CreatePaymentIntent(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
 	}
 
-	// Get order from database
-	orderID, err := uuid.Parse(req.OrderID)
+	checkoutID, err := paymentCheckoutID(req, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Where providers that redirect the buyer send them back to
+	returnURL := req.ReturnURL
+	if returnURL == "" {
+		returnURL = h.config.AppURL + "/checkout/return"
+	}
+	cancelURL := req.CancelURL
+	if cancelURL == "" {
+		cancelURL = h.config.AppURL + "/checkout/cancel"
+	}
+
+	var response types.CreatePaymentIntentResponse
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the checkout so concurrent requests can't both create an intent
+		var checkout models.Checkout
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Orders").
+			First(&checkout, "id = ? AND user_id = ?", checkoutID, claims.UserID).Error; err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "Checkout not found")
+		}
+		if checkout.Status != models.CheckoutStatusPending {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Checkout is already %s", checkout.Status))
+		}
+
+		provider, ok := h.providers[checkout.PaymentMethod]
+		if !ok {
+			return fiber.NewError(fiber.StatusConflict, "Checkout is not paid online")
+		}
+
+		amount, err := checkoutAmountCents(&checkout)
+		if err != nil {
+			return err
+		}
+
+		var existing models.Payment
+		err = tx.Where("checkout_id = ? AND status = ?", checkout.ID, types.PaymentStatusPending).
+			Order("created_at DESC").First(&existing).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			if existing.Provider == provider.Name() && existing.Amount.Cents == amount {
+				intent, err := provider.GetIntent(existing.ProviderID)
+				if err == nil && intent.Status.Payable() {
+					response = types.CreatePaymentIntentResponse{
+						ClientSecret:  intent.ClientSecret,
+						PaymentID:     existing.ID.String(),
+						Amount:        existing.Amount,
+						Currency:      existing.Amount.Currency,
+						NextActionURL: intent.NextActionURL,
+					}
+					return nil
+				}
+			}
+
+			// The orders changed since, or the intent can no longer be paid
+			if previous, err := h.providerByName(existing.Provider); err == nil {
+				if err := previous.CancelIntent(existing.ProviderID); err != nil {
+					log.Printf("Error cancelling stale payment intent %s: %v", existing.ProviderID, err)
+				}
+			}
+			if err := tx.Model(&existing).Update("status", types.PaymentStatusCancelled).Error; err != nil {
+				return err
+			}
+		}
+
+		// Create payment intent
+		intent, err := provider.CreateIntent(payments.IntentRequest{
+			Amount:      money.Cents(amount),
+			Description: req.Description,
+			Metadata: map[string]string{
+				"checkout_id": checkout.ID.String(),
+			},
+			ReturnURL: returnURL,
+			CancelURL: cancelURL,
+		})
+		if err != nil {
+			log.Printf("Error creating payment intent: %v", err)
+			return fiber.NewError(fiber.StatusBadGateway, "Failed to create payment intent")
+		}
+
+		// Create payment record in database
+		payment := models.Payment{
+			CheckoutID:    checkout.ID,
+			Amount:        money.Cents(amount),
+			Status:        string(types.PaymentStatusPending),
+			PaymentMethod: checkout.PaymentMethod,
+			Provider:      provider.Name(),
+			ProviderID:    intent.ID,
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return fmt.Errorf("failed to record payment intent %s: %w", intent.ID, err)
+		}
+
+		response = types.CreatePaymentIntentResponse{
+			ClientSecret:  intent.ClientSecret,
+			PaymentID:     payment.ID.String(),
+			Amount:        payment.Amount,
+			Currency:      payment.Amount.Currency,
+			NextActionURL: intent.NextActionURL,
+		}
+		return nil
+	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error creating payment: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create payment")
+	}
+
+	return c.JSON(response)
+}
+
+// paymentCheckoutID finds the checkout a payment request is for. A checkout
+// is paid as a whole, so an order ID stands for its checkout.
+func paymentCheckoutID(req types.CreatePaymentIntentRequest, userID uuid.UUID) (uuid.UUID, error) {
+	if req.CheckoutID != "" {
+		checkoutID, err := uuid.Parse(req.CheckoutID)
+		if err != nil {
+			return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid checkout ID")
+		}
+		return checkoutID, nil
 	}
 
+	orderID, err := uuid.Parse(req.OrderID)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
 	var order models.Order
-	if err := database.DB.First(&order, "id = ?", orderID).Error; err != nil {
-		return fiber.NewError(fiber.StatusNotFound, "Order not found")
+	if err := database.DB.First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "Order not found")
 	}
+	return order.CheckoutID, nil
+}
 
-	// Create payment intent
-	params := &stripe.PaymentIntentParams{
-		Amount:   stripe.Int64(int64(req.Amount * 100)), // Convert to cents
-		Currency: stripe.String(req.Currency),
-		Metadata: map[string]string{
-			"order_id": req.OrderID,
-		},
+// checkoutAmountCents is what the buyer owes for the orders of a checkout
+// that are still waiting for payment, after their store credit. It fails
+// once there is nothing left to pay or the reservation has expired.
+func checkoutAmountCents(checkout *models.Checkout) (int64, error) {
+	now := time.Now()
+	for _, order := range checkout.Orders {
+		if order.Status == models.OrderStatusPending && order.ReservedUntil != nil && order.ReservedUntil.Before(now) {
+			return 0, fiber.NewError(fiber.StatusConflict, "Checkout reservation has expired")
+		}
+	}
+	amount := checkoutAmountOwed(checkout)
+	if amount <= 0 {
+		return 0, fiber.NewError(fiber.StatusConflict, "Checkout has nothing left to pay for")
+	}
+	return amount, nil
+}
+
+// checkoutAmountOwed adds up what is left to pay online for the orders of a
+// checkout waiting for payment
+func checkoutAmountOwed(checkout *models.Checkout) int64 {
+	var amount int64
+	for _, order := range checkout.Orders {
+		if order.Status == models.OrderStatusPending {
+			amount += order.Total.Cents - order.Credit.Cents
+		}
+	}
+	return amount
+}
+
+// cancelPendingPayments cancels the payments of a checkout that are still
+// waiting for the buyer, so the checkout can't be paid once it is released.
+// It fails if one of them might still go through, e.g. because the buyer has
+// just paid or the provider couldn't be reached.
+func (h *PaymentHandler) cancelPendingPayments(tx *gorm.DB, checkoutID uuid.UUID) error {
+	var pending []models.Payment
+	if err := tx.Where("checkout_id = ? AND status = ?", checkoutID, types.PaymentStatusPending).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+
+	for i := range pending {
+		payment := &pending[i]
+		provider, err := h.providerByName(payment.Provider)
+		if err != nil {
+			return err
+		}
+		if err := provider.CancelIntent(payment.ProviderID); err != nil {
+			// Intents that can no longer be paid can't be cancelled either,
+			// which is only fine if they already were
+			intent, getErr := provider.GetIntent(payment.ProviderID)
+			if getErr != nil || intent.Status != payments.IntentCanceled {
+				return fmt.Errorf("failed to cancel payment %s: %w", payment.ID, err)
+			}
+		}
+		if err := tx.Model(payment).Update("status", types.PaymentStatusCancelled).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfirmPayment charges a payment method against one of the caller's
+// pending payments. The outcome arrives through the provider's webhook; the
+// response only says whether the buyer still has to authenticate.
+func (h *PaymentHandler) ConfirmPayment(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.ConfirmPaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
 	}
 
-	pi, err := paymentintent.New(params)
+	paymentID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
-		log.Printf("Error creating payment intent: %v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create payment intent")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid payment ID")
 	}
 
-	// Create payment record in database
-	payment := models.Payment{
-		OrderID:       orderID,
-		Amount:        req.Amount,
-		Currency:      req.Currency,
-		Status:        string(types.PaymentStatusPending),
-		PaymentMethod: order.PaymentMethod,
-		StripeID:      pi.ID,
+	var payment models.Payment
+	if err := database.DB.Joins("Checkout").
+		Where("payments.id = ? AND \"Checkout\".user_id = ?", paymentID, claims.UserID).
+		First(&payment).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Payment not found")
+	}
+	if payment.Status != string(types.PaymentStatusPending) {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Payment is already %s", payment.Status))
 	}
 
-	if err := database.DB.Create(&payment).Error; err != nil {
-		log.Printf("Error creating payment record: %v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create payment record")
+	provider, err := h.providerByName(payment.Provider)
+	if err != nil {
+		return fiber.NewError(fiber.StatusConflict, "Payment provider is no longer available")
 	}
 
-	return c.JSON(types.CreatePaymentIntentResponse{
-		ClientSecret: pi.ClientSecret,
-		PaymentID:    payment.ID.String(),
+	intent, err := provider.Confirm(payment.ProviderID, payments.ConfirmRequest{
+		PaymentMethod: req.PaymentMethod,
+		ReturnURL:     req.ReturnURL,
 	})
+	if err != nil {
+		log.Printf("Error confirming payment %s: %v", payment.ID, err)
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to confirm payment")
+	}
+
+	return c.JSON(types.ConfirmPaymentResponse{
+		PaymentID:     payment.ID.String(),
+		Status:        string(intent.Status),
+		NextActionURL: intent.NextActionURL,
+		Error:         intent.FailureMessage,
+	})
+}
+
+// providerByName returns the provider a payment was made with
+func (h *PaymentHandler) providerByName(name string) (payments.Provider, error) {
+	for _, provider := range h.providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("payment provider %q is not configured", name)
+}
+
+// findPayment finds the payment a provider event is about
+func (h *PaymentHandler) findPayment(provider, providerID string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := database.DB.Where("provider = ? AND provider_id = ?", provider, providerID).
+		First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+	return &payment, nil
 }
 
-// HandleWebhook handles Stripe webhook events
-func (h *PaymentHandler) HandleWebhook(c *fiber.Ctx) error {
-	payload := c.Body()
-	event, err := webhook.ConstructEvent(payload, c.Get("Stripe-Signature"), h.stripeKey)
+// handlePaymentSuccess settles the checkout a payment was for. A payment
+// that arrives once the checkout can no longer be sold as paid for, e.g.
+// after it expired or its orders changed, is refunded and kept for review.
+func (h *PaymentHandler) handlePaymentSuccess(provider string, intent *payments.Intent) error {
+	payment, err := h.findPayment(provider, intent.ID)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook signature")
+		return err
 	}
 
-	switch event.Type {
-	case "payment_intent.succeeded":
-		var pi stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &pi)
-		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "Invalid payment intent data")
+	var problem string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Wait for anything else changing the checkout to finish
+		var checkout models.Checkout
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Orders").
+			First(&checkout, "id = ?", payment.CheckoutID).Error; err != nil {
+			return err
 		}
-		if err := h.handlePaymentSuccess(&pi); err != nil {
-			log.Printf("Error handling payment success: %v", err)
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to process payment success")
+		if err := tx.First(payment, "id = ?", payment.ID).Error; err != nil {
+			return err
 		}
 
-	case "payment_intent.payment_failed":
-		var pi stripe.PaymentIntent
-		err := json.Unmarshal(event.Data.Raw, &pi)
-		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "Invalid payment intent data")
+		switch {
+		case payment.Status == string(types.PaymentStatusSuccess):
+			return nil
+		case payment.Status == string(types.PaymentStatusNeedsReview):
+			problem = payment.Error
+			return nil
+		case payment.Status != string(types.PaymentStatusPending):
+			problem = fmt.Sprintf("Payment was already %s", payment.Status)
+		case checkout.Status != models.CheckoutStatusPending:
+			problem = fmt.Sprintf("Checkout is already %s", checkout.Status)
+		case intent.Amount.Cents != payment.Amount.Cents:
+			problem = fmt.Sprintf("Paid %s, the payment was for %s", intent.Amount, payment.Amount)
+		case checkoutAmountOwed(&checkout) != payment.Amount.Cents:
+			problem = fmt.Sprintf("Paid %s, the checkout now comes to %s", payment.Amount, money.Cents(checkoutAmountOwed(&checkout)))
 		}
-		if err := h.handlePaymentFailure(&pi); err != nil {
-			log.Printf("Error handling payment failure: %v", err)
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to process payment failure")
+		if problem != "" {
+			return tx.Model(payment).Updates(map[string]interface{}{
+				"status": types.PaymentStatusNeedsReview,
+				"error":  problem,
+			}).Error
 		}
+
+		// Mark the checkout and every seller order in it as paid
+		if err := tx.Model(payment).Update("status", types.PaymentStatusSuccess).Error; err != nil {
+			return err
+		}
+		return settleCheckout(tx, checkout.ID, "Payment received")
+	})
+	if err != nil {
+		return fmt.Errorf("failed to settle payment: %w", err)
 	}
 
-	return c.SendStatus(fiber.StatusOK)
+	if problem != "" {
+		log.Printf("Refunding payment %s for review: %s", payment.ID, problem)
+		return h.refundPayment(payment, problem)
+	}
+	return nil
 }
 
-func (h *PaymentHandler) handlePaymentSuccess(pi *stripe.PaymentIntent) error {
-	orderID := pi.Metadata["order_id"]
-	var payment models.Payment
-	if err := database.DB.Where("stripe_id = ?", pi.ID).First(&payment).Error; err != nil {
-		return fmt.Errorf("payment not found: %w", err)
+// refundPayment gives the buyer back the whole of a payment we couldn't
+// accept, unless that was already done
+func (h *PaymentHandler) refundPayment(payment *models.Payment, reason string) error {
+	var refunds int64
+	if err := database.DB.Model(&models.Refund{}).Where("payment_id = ?", payment.ID).
+		Count(&refunds).Error; err != nil {
+		return err
 	}
-
-	// Update payment status
-	payment.Status = string(types.PaymentStatusSuccess)
-	if err := database.DB.Save(&payment).Error; err != nil {
-		return fmt.Errorf("failed to update payment: %w", err)
+	if refunds > 0 {
+		return nil
 	}
 
-	// Update order status
-	var order models.Order
-	if err := database.DB.First(&order, "id = ?", orderID).Error; err != nil {
-		return fmt.Errorf("order not found: %w", err)
+	provider, err := h.providerByName(payment.Provider)
+	if err != nil {
+		return err
 	}
-
-	order.Status = "paid"
-	if err := database.DB.Save(&order).Error; err != nil {
-		return fmt.Errorf("failed to update order: %w", err)
+	re, err := provider.Refund(payments.RefundRequest{
+		IntentID: payment.ProviderID,
+		Amount:   payment.Amount,
+		Metadata: map[string]string{
+			"payment_id": payment.ID.String(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to refund payment %s: %w", payment.ID, err)
 	}
 
+	record := models.Refund{
+		PaymentID:   payment.ID,
+		Amount:      payment.Amount,
+		Status:      re.Status,
+		ProviderID:  re.ID,
+		Reason:      reason,
+		InitiatedBy: models.ActorSystem,
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to record refund %s: %w", re.ID, err)
+	}
 	return nil
 }
 
-func (h *PaymentHandler) handlePaymentFailure(pi *stripe.PaymentIntent) error {
-	var payment models.Payment
-	if err := database.DB.Where("stripe_id = ?", pi.ID).First(&payment).Error; err != nil {
-		return fmt.Errorf("payment not found: %w", err)
+func (h *PaymentHandler) handlePaymentFailure(provider string, intent *payments.Intent) error {
+	payment, err := h.findPayment(provider, intent.ID)
+	if err != nil {
+		return err
 	}
 
 	// Update payment status
 	payment.Status = string(types.PaymentStatusFailed)
-	if pi.LastPaymentError != nil {
-		payment.Error = pi.LastPaymentError.Error()
-	}
+	payment.Error = intent.FailureMessage
 
-	if err := database.DB.Save(&payment).Error; err != nil {
+	if err := database.DB.Save(payment).Error; err != nil {
 		return fmt.Errorf("failed to update payment: %w", err)
 	}
 
+	// Put the products back on the market
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return releaseCheckout(tx, payment.CheckoutID, "Payment failed")
+	}); err != nil {
+		return fmt.Errorf("failed to release checkout: %w", err)
+	}
+
 	return nil
 }
+
+// RefundOrder refunds the buyer for a cancelled order out of its checkout's
+// payment. The refund is partial when the checkout has other orders. Store
+// credit isn't refunded here but goes back to the wallet with the sale, so
+// there is nothing to refund for orders paid in credit alone.
+func (h *PaymentHandler) RefundOrder(order *models.Order, actor models.OrderActor, reason string) (*models.Refund, error) {
+	amount := order.Total.Cents - order.Credit.Cents
+	if amount == 0 {
+		return nil, nil
+	}
+
+	var payment models.Payment
+	if err := database.DB.Where("checkout_id = ? AND status IN ?", order.CheckoutID, []string{
+		string(types.PaymentStatusSuccess),
+		string(types.PaymentStatusPartiallyRefunded),
+	}).First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("no successful payment for order: %w", err)
+	}
+
+	provider, err := h.providerByName(payment.Provider)
+	if err != nil {
+		return nil, err
+	}
+	re, err := provider.Refund(payments.RefundRequest{
+		IntentID: payment.ProviderID,
+		Amount:   money.Cents(amount),
+		Metadata: map[string]string{
+			"order_id": order.ID.String(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	record := models.Refund{
+		PaymentID:   payment.ID,
+		OrderID:     &order.ID,
+		Amount:      money.Cents(amount),
+		Status:      re.Status,
+		ProviderID:  re.ID,
+		Reason:      reason,
+		InitiatedBy: actor,
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to record refund %s: %w", re.ID, err)
+	}
+
+	return &record, nil
+}
+
+func (h *PaymentHandler) handleRefundUpdated(provider string, update *payments.RefundUpdate) error {
+	payment, err := h.findPayment(provider, update.IntentID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Sync the status of every refund the provider told us about
+		for _, re := range update.Refunds {
+			if err := tx.Model(&models.Refund{}).Where("provider_id = ?", re.ID).
+				Update("status", re.Status).Error; err != nil {
+				return err
+			}
+		}
+
+		// Payments refunded because they came too late never paid for the
+		// orders, so those are left alone and the payment stays for review
+		if payment.Status == string(types.PaymentStatusNeedsReview) {
+			return nil
+		}
+
+		payment.Status = string(types.PaymentStatusPartiallyRefunded)
+		if update.FullyRefunded {
+			payment.Status = string(types.PaymentStatusRefunded)
+		}
+		if err := tx.Save(payment).Error; err != nil {
+			return err
+		}
+		if payment.Status != string(types.PaymentStatusRefunded) {
+			return nil
+		}
+
+		// A full refund issued from the provider's dashboard cancels whatever
+		// hasn't shipped
+		var orders []models.Order
+		if err := tx.Where("checkout_id = ? AND status IN ?", payment.CheckoutID, []models.OrderStatus{
+			models.OrderStatusPaid,
+			models.OrderStatusReady,
+		}).Find(&orders).Error; err != nil {
+			return err
+		}
+		for i := range orders {
+			if err := restockOrder(tx, &orders[i]); err != nil {
+				return err
+			}
+			if err := transitionOrder(tx, &orders[i], models.OrderStatusCancelled, models.ActorSystem, nil, "Payment refunded"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
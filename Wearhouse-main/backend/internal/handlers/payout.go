@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/payouts"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PayoutHandler struct {
+	config   *configs.Config
+	provider payouts.Provider
+}
+
+func NewPayoutHandler(config *configs.Config) *PayoutHandler {
+	return &PayoutHandler{
+		config:   config,
+		provider: payouts.New(config),
+	}
+}
+
+// GetBalance returns what the caller has earned as a seller, from the ledger
+func (h *PayoutHandler) GetBalance(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", claims.UserID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	response := types.BalanceResponse{
+		Currency:               money.Currency,
+		PayoutAccountConnected: user.PayoutAccountID != "",
+	}
+	for account, balance := range map[models.LedgerAccount]*money.Money{
+		models.LedgerSellerPending:   &response.Pending,
+		models.LedgerSellerAvailable: &response.Available,
+		models.LedgerPayouts:         &response.PaidOut,
+	} {
+		amount, err := userLedgerBalance(database.DB, user.ID, account)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to get balance")
+		}
+		*balance = money.Cents(amount)
+	}
+
+	return c.JSON(response)
+}
+
+// CreatePayoutAccount opens the caller's account with the payout provider
+// the first time, and returns where they finish setting it up
+func (h *PayoutHandler) CreatePayoutAccount(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", claims.UserID).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if user.PayoutAccountID == "" {
+		accountID, err := h.provider.CreateAccount(payouts.AccountRequest{
+			Email:   user.Email,
+			Country: "CA",
+		})
+		if err != nil {
+			log.Printf("Error creating payout account for user %s: %v", user.ID, err)
+			return fiber.NewError(fiber.StatusBadGateway, "Failed to create payout account")
+		}
+
+		// Keep the first account if two requests race
+		result := database.DB.Model(&models.User{}).
+			Where("id = ? AND (payout_account_id IS NULL OR payout_account_id = '')", user.ID).
+			Update("payout_account_id", accountID)
+		if result.Error != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save payout account")
+		}
+		if result.RowsAffected == 0 {
+			if err := database.DB.First(&user, "id = ?", user.ID).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to get user")
+			}
+		} else {
+			user.PayoutAccountID = accountID
+		}
+	}
+
+	url, err := h.provider.OnboardingURL(user.PayoutAccountID,
+		h.config.AppURL+"/payouts/return", h.config.AppURL+"/payouts/refresh")
+	if err != nil {
+		log.Printf("Error getting payout onboarding link for user %s: %v", user.ID, err)
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to get payout onboarding link")
+	}
+
+	return c.JSON(types.PayoutAccountResponse{OnboardingURL: url})
+}
+
+// PayOutSellers pays every seller with a payout account their available
+// balance once it reaches the minimum. Payouts left pending by an earlier
+// run are sent again first; the provider won't pay the same one twice.
+func PayOutSellers(config *configs.Config) error {
+	provider := payouts.New(config)
+
+	var pending []models.Payout
+	if err := database.DB.Preload("User").
+		Where("status = ? AND provider = ?", models.PayoutStatusPending, provider.Name()).
+		Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		if err := sendPayout(provider, &pending[i]); err != nil {
+			log.Printf("Error sending payout %s: %v", pending[i].ID, err)
+		}
+	}
+
+	var sellerIDs []uuid.UUID
+	if err := database.DB.Model(&models.LedgerEntry{}).
+		Joins("JOIN users ON users.id = ledger_entries.user_id").
+		Where("ledger_entries.account = ? AND users.payout_account_id <> ''", models.LedgerSellerAvailable).
+		Group("ledger_entries.user_id").
+		Having("SUM(ledger_entries.amount_cents) >= ?", config.PayoutMinimumCents).
+		Pluck("ledger_entries.user_id", &sellerIDs).Error; err != nil {
+		return err
+	}
+
+	for _, sellerID := range sellerIDs {
+		payout, err := createPayout(provider, sellerID, config.PayoutMinimumCents)
+		if err != nil {
+			log.Printf("Error creating payout for seller %s: %v", sellerID, err)
+			continue
+		}
+		if payout == nil {
+			continue
+		}
+		if err := sendPayout(provider, payout); err != nil {
+			log.Printf("Error sending payout %s: %v", payout.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// createPayout moves a seller's available balance into a new pending
+// payout, or returns nil if there isn't enough to pay out
+func createPayout(provider payouts.Provider, sellerID uuid.UUID, minimum int64) (*models.Payout, error) {
+	var payout *models.Payout
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the seller so the same balance can't be paid out twice
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", sellerID).Error; err != nil {
+			return err
+		}
+		available, err := userLedgerBalance(tx, sellerID, models.LedgerSellerAvailable)
+		if err != nil {
+			return err
+		}
+		if available < minimum || user.PayoutAccountID == "" {
+			return nil
+		}
+
+		payout = &models.Payout{
+			UserID:   sellerID,
+			User:     user,
+			Amount:   money.Cents(available),
+			Status:   models.PayoutStatusPending,
+			Provider: provider.Name(),
+		}
+		if err := tx.Omit("User").Create(payout).Error; err != nil {
+			return err
+		}
+		return postLedger(tx, nil, &payout.ID, "Payout",
+			ledgerLine{account: models.LedgerSellerAvailable, userID: &sellerID, amount: -available},
+			ledgerLine{account: models.LedgerPayouts, userID: &sellerID, amount: available},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payout, nil
+}
+
+// sendPayout transfers a pending payout to the seller. A rejected transfer
+// fails the payout and gives the money back to the seller's available
+// balance for the next run. Any other error leaves the payout pending, as the
+// transfer may have been made; the next run sends it again under the same
+// idempotency key, which can't pay twice.
+func sendPayout(provider payouts.Provider, payout *models.Payout) error {
+	transfer, err := provider.Transfer(payouts.TransferRequest{
+		AccountID:      payout.User.PayoutAccountID,
+		Amount:         payout.Amount,
+		IdempotencyKey: payout.ID.String(),
+		Metadata: map[string]string{
+			"payout_id": payout.ID.String(),
+		},
+	})
+	if err != nil && !errors.Is(err, payouts.ErrRejected) {
+		if err := database.DB.Model(payout).Update("error", err.Error()).Error; err != nil {
+			log.Printf("Error saving transfer error on payout %s: %v", payout.ID, err)
+		}
+		return fmt.Errorf("transfer may not have been made, will retry: %w", err)
+	}
+	if err != nil {
+		transferErr := err
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(payout).Updates(map[string]interface{}{
+				"status": models.PayoutStatusFailed,
+				"error":  transferErr.Error(),
+			}).Error; err != nil {
+				return err
+			}
+			return postLedger(tx, nil, &payout.ID, "Payout failed",
+				ledgerLine{account: models.LedgerPayouts, userID: &payout.UserID, amount: -payout.Amount.Cents},
+				ledgerLine{account: models.LedgerSellerAvailable, userID: &payout.UserID, amount: payout.Amount.Cents},
+			)
+		}); err != nil {
+			return fmt.Errorf("failed to record failed transfer (%v): %w", transferErr, err)
+		}
+		return fmt.Errorf("transfer failed: %w", transferErr)
+	}
+
+	return database.DB.Model(payout).Updates(map[string]interface{}{
+		"status":      models.PayoutStatusPaid,
+		"provider_id": transfer.ID,
+		"error":       "",
+	}).Error
+}
@@ -7,11 +7,13 @@ import (
 	"strings"
 	"wearhouse/internal/database"
 	"wearhouse/internal/models"
+	"wearhouse/internal/money"
 	"wearhouse/internal/types"
 	"wearhouse/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateProduct handles the creation of a new product
@@ -19,8 +21,9 @@ func CreateProduct(c *fiber.Ctx) error {
 	// Get user from context (set by auth middleware)
 	claims := c.Locals("user").(*utils.JWTClaims)
 
-	var title, description, category, size, brand, condition string
+	var title, description, category, size, brand, condition, listingType string
 	var price float64
+	var acceptsCash bool
 	var err error
 
 	// Try to parse JSON first
@@ -32,7 +35,9 @@ func CreateProduct(c *fiber.Ctx) error {
 		size = req.Size
 		brand = req.Brand
 		condition = req.Condition
+		listingType = req.ListingType
 		price = req.Price
+		acceptsCash = req.AcceptsCash
 	} else {
 		// If JSON parsing fails, try form data
 		title = c.FormValue("title")
@@ -41,6 +46,8 @@ func CreateProduct(c *fiber.Ctx) error {
 		size = c.FormValue("size")
 		brand = c.FormValue("brand")
 		condition = c.FormValue("condition")
+		listingType = c.FormValue("listing_type")
+		acceptsCash = c.FormValue("accepts_cash") == "true"
 		priceStr := c.FormValue("price")
 
 		// Convert price to float64
@@ -54,6 +61,18 @@ func CreateProduct(c *fiber.Ctx) error {
 
 	log.Printf("Received request: title=%s, description=%s", title, description)
 
+	// Listings are for sale unless stated otherwise
+	if listingType == "" {
+		listingType = string(models.Sale)
+	}
+	switch models.ListingType(strings.ToUpper(listingType)) {
+	case models.Sale, models.Trade, models.Free:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid listing type",
+		})
+	}
+
 	// Create product
 	product := models.Product{
 		UserID:      claims.UserID,
@@ -63,8 +82,10 @@ func CreateProduct(c *fiber.Ctx) error {
 		Size:        size,
 		Brand:       brand,
 		Condition:   condition,
-		Price:       price,
+		ListingType: models.ListingType(strings.ToUpper(listingType)),
+		Price:       money.FromFloat(price),
 		IsAvailable: true,
+		AcceptsCash: acceptsCash,
 	}
 
 	// Handle image upload if present
@@ -139,6 +160,15 @@ func GetProduct(c *fiber.Ctx) error {
 	return c.JSON(toProductResponse(&product))
 }
 
+// productSortColumns maps the sort_by values clients send to the columns
+// they sort on. Prices are stored in cents.
+var productSortColumns = map[string]string{
+	"price":      "price_cents",
+	"title":      "title",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 // ListProducts retrieves a list of products with filters
 func ListProducts(c *fiber.Ctx) error {
 	var filters types.ProductFilters
@@ -160,23 +190,16 @@ func ListProducts(c *fiber.Ctx) error {
 	query := database.DB.Model(&models.Product{})
 
 	// Apply filters
-	if filters.Category != "" {
-		query = query.Where("category = ?", filters.Category)
-	}
-	if filters.Size != "" {
-		query = query.Where("size = ?", filters.Size)
-	}
-	if filters.Brand != "" {
-		query = query.Where("brand = ?", filters.Brand)
-	}
-	if filters.Condition != "" {
-		query = query.Where("condition = ?", filters.Condition)
-	}
+	query = applyMultiValueFilter(query, "category", filters.Category, nil)
+	query = applyMultiValueFilter(query, "size", filters.Size, nil)
+	query = applyMultiValueFilter(query, "brand_normalized", filters.Brand, models.NormalizeBrand)
+	query = applyMultiValueFilter(query, "condition", filters.Condition, nil)
+	query = applyMultiValueFilter(query, "listing_type", filters.ListingType, strings.ToUpper)
 	if filters.MinPrice != nil {
-		query = query.Where("price >= ?", filters.MinPrice)
+		query = query.Where("price_cents >= ?", money.FromFloat(*filters.MinPrice).Cents)
 	}
 	if filters.MaxPrice != nil {
-		query = query.Where("price <= ?", filters.MaxPrice)
+		query = query.Where("price_cents <= ?", money.FromFloat(*filters.MaxPrice).Cents)
 	}
 	if filters.Search != "" {
 		search := "%" + filters.Search + "%"
@@ -199,11 +222,17 @@ func ListProducts(c *fiber.Ctx) error {
 	query = query.Offset(offset).Limit(filters.PerPage)
 
 	if filters.SortBy != "" {
+		column, ok := productSortColumns[filters.SortBy]
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid sort field",
+			})
+		}
 		order := "asc"
 		if filters.SortOrder == "desc" {
 			order = "desc"
 		}
-		query = query.Order(filters.SortBy + " " + order)
+		query = query.Order(column + " " + order)
 	} else {
 		query = query.Order("created_at desc")
 	}
@@ -316,7 +345,10 @@ func UpdateProduct(c *fiber.Ctx) error {
 		product.Condition = *req.Condition
 	}
 	if req.Price != nil {
-		product.Price = *req.Price
+		product.Price = money.FromFloat(*req.Price)
+	}
+	if req.AcceptsCash != nil {
+		product.AcceptsCash = *req.AcceptsCash
 	}
 
 	// Save to database
@@ -368,6 +400,41 @@ func DeleteProduct(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// applyMultiValueFilter restricts column to the included values and away from
+// the excluded ones. Values may be comma-separated or repeated, and a leading
+// "-" marks a value as excluded. normalize, if set, is applied to every value.
+func applyMultiValueFilter(query *gorm.DB, column string, values []string, normalize func(string) string) *gorm.DB {
+	var include, exclude []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			negate := strings.HasPrefix(part, "-")
+			if negate {
+				part = strings.TrimSpace(part[1:])
+			}
+			if part == "" {
+				continue
+			}
+			if normalize != nil {
+				part = normalize(part)
+			}
+			if negate {
+				exclude = append(exclude, part)
+			} else {
+				include = append(include, part)
+			}
+		}
+	}
+
+	if len(include) > 0 {
+		query = query.Where(column+" IN ?", include)
+	}
+	if len(exclude) > 0 {
+		query = query.Where(column+" NOT IN ?", exclude)
+	}
+	return query
+}
+
 // Helper function to convert Product model to ProductResponse
 func toProductResponse(product *models.Product) *types.ProductResponse {
 	return &types.ProductResponse{
@@ -379,8 +446,10 @@ func toProductResponse(product *models.Product) *types.ProductResponse {
 		Size:        product.Size,
 		Brand:       product.Brand,
 		Condition:   product.Condition,
+		ListingType: string(product.ListingType),
 		Price:       product.Price,
 		IsAvailable: product.IsAvailable,
+		AcceptsCash: product.AcceptsCash,
 		Images:      product.Images,
 		CreatedAt:   product.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   product.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
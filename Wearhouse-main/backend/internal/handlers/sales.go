@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// soldStatuses are the statuses of orders that count as a sale
+var soldStatuses = []models.OrderStatus{
+	models.OrderStatusPaid,
+	models.OrderStatusShipped,
+	models.OrderStatusReady,
+	models.OrderStatusDelivered,
+}
+
+// GetSales returns the orders for the seller's products, optionally filtered
+// by ?status=, along with their sales totals
+func (h *OrderHandler) GetSales(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	query := database.DB.Scopes(withOrderDetails).
+		Where("seller_id = ?", claims.UserID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.Order
+	if err := query.Order("created_at desc").Find(&orders).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get sales")
+	}
+
+	summary, err := salesSummary(claims.UserID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get sales summary")
+	}
+
+	// Convert to response type
+	response := types.SalesListResponse{
+		Orders:  make([]types.OrderResponse, len(orders)),
+		Summary: *summary,
+	}
+	for i, order := range orders {
+		response.Orders[i] = *orderToResponse(&order)
+	}
+
+	return c.JSON(response)
+}
+
+// GetSale returns a single order for the seller's products
+func (h *OrderHandler) GetSale(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	order, err := findSale(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(orderToResponse(order))
+}
+
+// MarkShipped records that the seller has shipped a paid order
+func (h *OrderHandler) MarkShipped(c *fiber.Ctx) error {
+	return h.sellerTransition(c, models.OrderStatusShipped)
+}
+
+// MarkReadyForPickup records that a paid order is ready to be collected
+func (h *OrderHandler) MarkReadyForPickup(c *fiber.Ctx) error {
+	return h.sellerTransition(c, models.OrderStatusReady)
+}
+
+// CancelSale lets the seller cancel an order before it ships, refunding the
+// buyer if they already paid
+func (h *OrderHandler) CancelSale(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CancelOrderRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	order, err := findSale(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.cancelOrder(order, models.ActorSeller, &claims.UserID, req.Reason, false); err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error cancelling order %s: %v", order.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to cancel order")
+	}
+
+	order, err = findSale(order.ID.String(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(orderToResponse(order))
+}
+
+// sellerTransition moves one of the seller's orders to status to
+func (h *OrderHandler) sellerTransition(c *fiber.Ctx, to models.OrderStatus) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.SellerActionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	order, err := findSale(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return transitionOrder(tx, order, to, models.ActorSeller, &claims.UserID, req.Reason)
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update order status")
+	}
+
+	order, err = findSale(order.ID.String(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(orderToResponse(order))
+}
+
+// findSale loads an order if sellerID sold it
+func findSale(id string, sellerID uuid.UUID) (*models.Order, error) {
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	var order models.Order
+	if err := database.DB.Scopes(withOrderDetails).
+		Where("id = ? AND seller_id = ?", orderID, sellerID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Order not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to get order")
+	}
+
+	return &order, nil
+}
+
+// salesSummary totals the seller's sales. Platform fees and sales tax don't
+// go to the seller, so they are left out, while coupon discounts are made
+// up by us.
+func salesSummary(sellerID uuid.UUID) (*types.SalesSummary, error) {
+	var summary types.SalesSummary
+	var grossCents, pendingCents int64
+
+	if err := database.DB.Model(&models.Order{}).
+		Where("seller_id = ? AND status IN ?", sellerID, soldStatuses).
+		Select("COALESCE(SUM(total_cents + discount_cents - fee_cents - tax_cents), 0)").Scan(&grossCents).Error; err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.seller_id = ? AND orders.status IN ?", sellerID, soldStatuses).
+		Select("COALESCE(SUM(order_items.quantity), 0)").Scan(&summary.ItemsSold).Error; err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Model(&models.Order{}).
+		Where("seller_id = ? AND status IN ?", sellerID, []models.OrderStatus{
+			models.OrderStatusPaid,
+			models.OrderStatusShipped,
+			models.OrderStatusReady,
+		}).
+		Select("COALESCE(SUM(total_cents + discount_cents - fee_cents - tax_cents), 0)").Scan(&pendingCents).Error; err != nil {
+		return nil, err
+	}
+
+	summary.GrossRevenue = money.Cents(grossCents)
+	summary.PendingPayouts = money.Cents(pendingCents)
+	return &summary, nil
+}
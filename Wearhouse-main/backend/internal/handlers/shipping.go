@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/shipping"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetShippingRates quotes the carrier's services for shipping one of the
+// seller's orders from one of their addresses
+func (h *OrderHandler) GetShippingRates(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.ShippingRatesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	order, err := findSale(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	rateReq, err := shippingRateRequest(order, req.FromAddressID.String(), req.Parcel)
+	if err != nil {
+		return err
+	}
+
+	rates, err := h.carrier.Rates(*rateReq)
+	if err != nil {
+		log.Printf("Error getting shipping rates for order %s: %v", order.ID, err)
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to get shipping rates")
+	}
+
+	response := make([]types.ShippingRateResponse, len(rates))
+	for i, rate := range rates {
+		response[i] = types.ShippingRateResponse{
+			Carrier:     h.carrier.Name(),
+			ServiceCode: rate.ServiceCode,
+			ServiceName: rate.ServiceName,
+			Price:       money.FromFloat(rate.Price),
+			Currency:    rate.Currency,
+			TransitDays: rate.TransitDays,
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// CreateShipment ships one of the seller's paid orders. The seller either
+// buys a label for one of the quoted services, or enters the tracking number
+// of a label they bought elsewhere.
+func (h *OrderHandler) CreateShipment(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.CreateShipmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	order, err := findSale(c.Params("id"), claims.UserID)
+	if err != nil {
+		return err
+	}
+	if order.Shipment != nil {
+		return fiber.NewError(fiber.StatusConflict, "Order has already been shipped")
+	}
+
+	// Check before a label is paid for
+	if err := order.Status.CheckTransition(models.OrderStatusShipped, models.ActorSeller); err != nil {
+		return transitionError(order.Status, models.OrderStatusShipped, models.ActorSeller, err)
+	}
+
+	shipment := models.Shipment{
+		OrderID: order.ID,
+		Status:  models.ShipmentLabelCreated,
+	}
+	switch {
+	case req.ServiceCode != "":
+		if req.FromAddressID == nil || req.Parcel == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "From address and parcel are required to buy a label")
+		}
+		rateReq, err := shippingRateRequest(order, req.FromAddressID.String(), *req.Parcel)
+		if err != nil {
+			return err
+		}
+
+		label, err := h.carrier.CreateLabel(shipping.LabelRequest{
+			RateRequest: *rateReq,
+			ServiceCode: req.ServiceCode,
+			Reference:   order.ID.String(),
+		})
+		if err != nil {
+			log.Printf("Error creating shipping label for order %s: %v", order.ID, err)
+			return fiber.NewError(fiber.StatusBadGateway, "Failed to create shipping label")
+		}
+
+		shipment.Carrier = h.carrier.Name()
+		shipment.ServiceCode = req.ServiceCode
+		shipment.TrackingNumber = label.TrackingNumber
+		shipment.LabelURL = label.LabelURL
+		shipment.Cost = money.FromFloat(label.Price)
+
+	case req.TrackingNumber != "":
+		if req.Carrier == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "Carrier is required with a tracking number")
+		}
+		shipment.Carrier = strings.ToLower(strings.TrimSpace(req.Carrier))
+		shipment.TrackingNumber = strings.TrimSpace(req.TrackingNumber)
+
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Either a service code or a tracking number is required")
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&shipment).Error; err != nil {
+			return err
+		}
+		return transitionOrder(tx, order, models.OrderStatusShipped, models.ActorSeller, &claims.UserID,
+			"Shipped with "+shipment.Carrier+", tracking "+shipment.TrackingNumber)
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		// The label may already be paid for, keep enough to find it again
+		log.Printf("Error recording shipment %s %s for order %s: %v", shipment.Carrier, shipment.TrackingNumber, order.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record shipment")
+	}
+
+	order, err = findSale(order.ID.String(), claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(orderToResponse(order))
+}
+
+// shippingRateRequest describes shipping an order from one of the seller's
+// addresses to the buyer
+func shippingRateRequest(order *models.Order, fromAddressID string, parcel types.ParcelRequest) (*shipping.RateRequest, error) {
+	if order.FulfilmentMethod != models.FulfilmentShip {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Meetup orders are handed over, not shipped")
+	}
+	if order.ShippingAddrID == nil {
+		return nil, fiber.NewError(fiber.StatusConflict, "Order has no structured shipping address, enter a tracking number instead")
+	}
+
+	from, err := findAddress(fromAddressID, order.SellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &shipping.RateRequest{
+		From: from.PostalAddress,
+		To:   order.ShippingAddress,
+		Parcel: shipping.Parcel{
+			WeightGrams: parcel.WeightGrams,
+			LengthCm:    parcel.LengthCm,
+			WidthCm:     parcel.WidthCm,
+			HeightCm:    parcel.HeightCm,
+		},
+	}, nil
+}
+
+// PollShipments checks the tracking of every parcel still on its way and
+// delivers the orders whose parcel has arrived. Only shipments made with the
+// configured carrier can be tracked. Each run makes its own carrier, which
+// is fine as carriers keep no state between calls.
+func PollShipments(config *configs.Config) error {
+	carrier := shipping.New(config)
+
+	var shipments []models.Shipment
+	if err := database.DB.Where("carrier = ? AND status <> ?", carrier.Name(), models.ShipmentDelivered).
+		Find(&shipments).Error; err != nil {
+		return err
+	}
+
+	for i := range shipments {
+		shipment := &shipments[i]
+
+		status, err := carrier.Track(shipment.TrackingNumber)
+		if err != nil {
+			log.Printf("Error tracking shipment %s: %v", shipment.TrackingNumber, err)
+			continue
+		}
+
+		now := time.Now()
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(shipment).Updates(map[string]interface{}{
+				"status":          models.ShipmentStatus(status.State),
+				"status_detail":   status.Description,
+				"last_checked_at": now,
+				"delivered_at":    status.DeliveredAt,
+			}).Error; err != nil {
+				return err
+			}
+			if status.State != shipping.TrackingDelivered {
+				return nil
+			}
+
+			var order models.Order
+			if err := tx.First(&order, "id = ?", shipment.OrderID).Error; err != nil {
+				return err
+			}
+			if !trackingDelivers(&order, status) {
+				return nil
+			}
+			return transitionOrder(tx, &order, models.OrderStatusDelivered, models.ActorSystem, nil,
+				"Delivered according to "+shipment.Carrier+" tracking")
+		})
+		if err != nil {
+			log.Printf("Error updating shipment %s: %v", shipment.TrackingNumber, err)
+		}
+	}
+
+	return nil
+}
+
+// trackingDelivers reports whether a parcel's tracking status delivers its
+// order. Orders that moved on some other way, e.g. confirmed by the buyer,
+// are left alone.
+func trackingDelivers(order *models.Order, status *shipping.TrackingStatus) bool {
+	return status.State == shipping.TrackingDelivered && order.Status == models.OrderStatusShipped
+}
+
+func shipmentToResponse(shipment *models.Shipment) *types.ShipmentResponse {
+	if shipment == nil {
+		return nil
+	}
+	response := &types.ShipmentResponse{
+		ID:             shipment.ID,
+		Carrier:        shipment.Carrier,
+		ServiceCode:    shipment.ServiceCode,
+		TrackingNumber: shipment.TrackingNumber,
+		LabelURL:       shipment.LabelURL,
+		Status:         string(shipment.Status),
+		StatusDetail:   shipment.StatusDetail,
+		DeliveredAt:    shipment.DeliveredAt,
+		CreatedAt:      shipment.CreatedAt,
+	}
+	if !shipment.Cost.IsZero() {
+		response.Cost = &shipment.Cost
+	}
+	return response
+}
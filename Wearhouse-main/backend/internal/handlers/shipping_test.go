@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+	"wearhouse/internal/models"
+	"wearhouse/internal/shipping"
+)
+
+func TestFakeDeliveryDeliversShippedOrder(t *testing.T) {
+	shipped := time.Now().Add(-time.Hour)
+	label, err := (&shipping.Fake{Now: func() time.Time { return shipped }}).CreateLabel(shipping.LabelRequest{
+		RateRequest: shipping.RateRequest{Parcel: shipping.Parcel{WeightGrams: 500}},
+		ServiceCode: "FAKE.REG",
+	})
+	if err != nil {
+		t.Fatalf("CreateLabel: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		status models.OrderStatus
+		now    time.Time
+		want   bool
+	}{
+		{"shipped order, parcel delivered", models.OrderStatusShipped, time.Now(), true},
+		{"shipped order, parcel on its way", models.OrderStatusShipped, shipped, false},
+		{"buyer already confirmed it", models.OrderStatusDelivered, time.Now(), false},
+		{"cancelled order", models.OrderStatusCancelled, time.Now(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A new carrier per poll, as PollShipments makes
+			carrier := shipping.NewFake()
+			now := tt.now
+			carrier.Now = func() time.Time { return now }
+			status, err := carrier.Track(label.TrackingNumber)
+			if err != nil {
+				t.Fatalf("Track: %v", err)
+			}
+
+			order := models.Order{Status: tt.status, FulfilmentMethod: models.FulfilmentShip}
+			if got := trackingDelivers(&order, status); got != tt.want {
+				t.Fatalf("trackingDelivers = %v, want %v", got, tt.want)
+			}
+			if tt.want {
+				if err := order.Status.CheckTransition(models.OrderStatusDelivered, models.ActorSystem); err != nil {
+					t.Errorf("tracking can't deliver the order: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TaxHandler struct {
+	config *configs.Config
+}
+
+func NewTaxHandler(config *configs.Config) *TaxHandler {
+	return &TaxHandler{
+		config: config,
+	}
+}
+
+// GetTaxRates lists every tax rate, including past and future ones (admin)
+func (h *TaxHandler) GetTaxRates(c *fiber.Ctx) error {
+	var rates []models.TaxRate
+	if err := database.DB.Order("province asc, name asc, effective_from desc").Find(&rates).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get tax rates")
+	}
+
+	response := make([]types.TaxRateResponse, len(rates))
+	for i := range rates {
+		response[i] = taxRateToResponse(&rates[i])
+	}
+
+	return c.JSON(response)
+}
+
+// CreateTaxRate adds a new rate for a tax in a province (admin). The old
+// rate stays in the table for the orders taxed at it; a rate of zero ends a
+// tax.
+func (h *TaxHandler) CreateTaxRate(c *fiber.Ctx) error {
+	var req types.CreateTaxRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	rate := models.TaxRate{
+		Province:      req.Province,
+		Name:          strings.ToUpper(strings.TrimSpace(req.Name)),
+		RatePPM:       req.RatePPM,
+		AppliesToFees: true,
+		EffectiveFrom: time.Now(),
+	}
+	if req.AppliesToFees != nil {
+		rate.AppliesToFees = *req.AppliesToFees
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(rate.EffectiveFrom) {
+			return fiber.NewError(fiber.StatusBadRequest, "Tax rates can't take effect in the past")
+		}
+		rate.EffectiveFrom = *req.EffectiveFrom
+	}
+
+	if err := database.DB.Create(&rate).Error; err != nil {
+		log.Printf("Error creating tax rate: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create tax rate")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(taxRateToResponse(&rate))
+}
+
+// GetReceipt returns the receipt of one of the caller's paid orders, as the
+// buyer or the seller
+func (h *OrderHandler) GetReceipt(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid order ID")
+	}
+
+	order, _, err := findOrderForParty(orderID, claims.UserID)
+	if err != nil {
+		return err
+	}
+	switch order.Status {
+	case models.OrderStatusPending, models.OrderStatusAwaitingHandoff, models.OrderStatusCancelled:
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Order is %s, there is no receipt", order.Status))
+	}
+	if err := database.DB.Preload("Items").Preload("TaxLines").First(order, "id = ?", order.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load order")
+	}
+
+	receipt := types.ReceiptResponse{
+		OrderID:       order.ID,
+		CheckoutID:    order.CheckoutID,
+		PaymentMethod: order.PaymentMethod,
+		Currency:      money.Currency,
+		Discount:      order.Discount,
+		Fee:           order.Fee,
+		Tax:           order.Tax,
+		Total:         order.Total,
+		OrderedAt:     order.CreatedAt,
+	}
+	for _, item := range order.Items {
+		price := item.Price.Mul(item.Quantity)
+		receipt.SellerName = item.SellerName
+		receipt.Subtotal = receipt.Subtotal.Add(price)
+		receipt.Lines = append(receipt.Lines, types.ReceiptLineResponse{
+			Title:    item.Title,
+			Quantity: item.Quantity,
+			Price:    price,
+			Fee:      item.Fee,
+			Discount: item.Discount,
+		})
+	}
+
+	// Receipts show each tax once, whatever it was charged on
+	for _, line := range order.TaxLines {
+		found := false
+		for i := range receipt.Taxes {
+			tax := &receipt.Taxes[i]
+			if tax.Name == line.Name && tax.Province == line.Province && tax.RatePercent == line.RatePercent() {
+				tax.Amount = tax.Amount.Add(line.Amount)
+				found = true
+				break
+			}
+		}
+		if !found {
+			receipt.Taxes = append(receipt.Taxes, types.ReceiptTaxResponse{
+				Name:        line.Name,
+				Province:    line.Province,
+				RatePercent: line.RatePercent(),
+				Amount:      line.Amount,
+			})
+		}
+	}
+
+	return c.JSON(receipt)
+}
+
+// currentTaxRates returns the taxes charged in a province now, by the
+// latest rate of each that has taken effect
+func currentTaxRates(db *gorm.DB, province string) ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	err := db.Raw(`SELECT DISTINCT ON (name) * FROM tax_rates
+		WHERE province = ? AND effective_from <= ?
+		ORDER BY name, effective_from DESC, created_at DESC`, province, time.Now()).
+		Scan(&rates).Error
+	return rates, err
+}
+
+// taxOrderItem works out the taxes on an order item and its fee. Goods are
+// only taxed when they are shipped, on their price less any coupon discount.
+func taxOrderItem(rates []models.TaxRate, province string, item *models.OrderItem, taxGoods bool) []models.TaxLine {
+	var lines []models.TaxLine
+	add := func(rate *models.TaxRate, base models.TaxBase, taxable money.Money) {
+		if taxable.Cents <= 0 || rate.RatePPM == 0 {
+			return
+		}
+		lines = append(lines, models.TaxLine{
+			OrderItemID: item.ID,
+			Base:        base,
+			TaxRateID:   rate.ID,
+			Name:        rate.Name,
+			Province:    province,
+			RatePPM:     rate.RatePPM,
+			Taxable:     taxable,
+			Amount:      rate.Tax(taxable),
+		})
+	}
+
+	for i := range rates {
+		if taxGoods {
+			add(&rates[i], models.TaxBaseItem, item.Price.Mul(item.Quantity).Sub(item.Discount))
+		}
+		if rates[i].AppliesToFees {
+			add(&rates[i], models.TaxBaseFee, item.Fee)
+		}
+	}
+	return lines
+}
+
+func taxRateToResponse(rate *models.TaxRate) types.TaxRateResponse {
+	return types.TaxRateResponse{
+		ID:            rate.ID,
+		Province:      rate.Province,
+		Name:          rate.Name,
+		RatePPM:       rate.RatePPM,
+		RatePercent:   rate.RatePercent(),
+		AppliesToFees: rate.AppliesToFees,
+		EffectiveFrom: rate.EffectiveFrom,
+		CreatedAt:     rate.CreatedAt,
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/types"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type UserHandler struct {
+	config *configs.Config
+}
+
+func NewUserHandler(config *configs.Config) *UserHandler {
+	return &UserHandler{
+		config: config,
+	}
+}
+
+// UpdateSuspension suspends or reinstates a user (admin). A suspended
+// seller's listings can't be bought; buyers who have them in their cart are
+// told when they validate it.
+func (h *UserHandler) UpdateSuspension(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req types.UserSuspensionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	result := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("is_suspended", *req.Suspended)
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update user")
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
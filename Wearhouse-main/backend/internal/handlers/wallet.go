@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"wearhouse/configs"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+	"wearhouse/internal/types"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WalletHandler struct {
+	config *configs.Config
+}
+
+func NewWalletHandler(config *configs.Config) *WalletHandler {
+	return &WalletHandler{
+		config: config,
+	}
+}
+
+// GetWallet returns the caller's store credit and its transaction history,
+// from the ledger
+func (h *WalletHandler) GetWallet(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	response, err := walletToResponse(database.DB, claims.UserID)
+	if err != nil {
+		log.Printf("Error getting wallet for user %s: %v", claims.UserID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get wallet")
+	}
+
+	return c.JSON(response)
+}
+
+// DepositEarnings moves the caller's available earnings into their wallet,
+// to spend on the platform instead of having them paid out
+func (h *WalletHandler) DepositEarnings(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*utils.JWTClaims)
+
+	var req types.WalletDepositRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := validate.Struct(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request data")
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the user so the same earnings can't also be paid out
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&models.User{}, "id = ?", claims.UserID).Error; err != nil {
+			return err
+		}
+		available, err := userLedgerBalance(tx, claims.UserID, models.LedgerSellerAvailable)
+		if err != nil {
+			return err
+		}
+
+		amount := req.Amount.Cents
+		if amount == 0 {
+			amount = available
+		}
+		if amount == 0 {
+			return fiber.NewError(fiber.StatusConflict, "There are no earnings available")
+		}
+		if amount > available {
+			return fiber.NewError(fiber.StatusConflict, "Amount is more than the earnings available")
+		}
+
+		return postLedger(tx, nil, nil, "Earnings kept as store credit",
+			ledgerLine{account: models.LedgerSellerAvailable, userID: &claims.UserID, amount: -amount},
+			ledgerLine{account: models.LedgerStoreCredit, userID: &claims.UserID, amount: amount},
+		)
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		log.Printf("Error depositing earnings for user %s: %v", claims.UserID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to deposit earnings")
+	}
+
+	response, err := walletToResponse(database.DB, claims.UserID)
+	if err != nil {
+		log.Printf("Error getting wallet for user %s: %v", claims.UserID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get wallet")
+	}
+
+	return c.JSON(response)
+}
+
+// walletCredit returns a user's store credit balance and how much of it is
+// held by orders waiting for payment. The held credit is spent once they
+// are paid, or freed if they are cancelled.
+func walletCredit(tx *gorm.DB, userID uuid.UUID) (balance, held int64, err error) {
+	if balance, err = userLedgerBalance(tx, userID, models.LedgerStoreCredit); err != nil {
+		return 0, 0, err
+	}
+	err = tx.Model(&models.Order{}).
+		Where("user_id = ? AND status = ?", userID, models.OrderStatusPending).
+		Select("COALESCE(SUM(credit_cents), 0)").Scan(&held).Error
+	return balance, held, err
+}
+
+func walletToResponse(db *gorm.DB, userID uuid.UUID) (*types.WalletResponse, error) {
+	balance, held, err := walletCredit(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.LedgerEntry
+	if err := db.Where("user_id = ? AND account = ?", userID, models.LedgerStoreCredit).
+		Order("created_at desc").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	response := &types.WalletResponse{
+		Currency:     money.Currency,
+		Balance:      money.Cents(balance),
+		Held:         money.Cents(held),
+		Available:    money.Cents(balance - held),
+		Transactions: make([]types.WalletTransactionResponse, len(entries)),
+	}
+	for i, entry := range entries {
+		response.Transactions[i] = types.WalletTransactionResponse{
+			ID:          entry.ID,
+			Amount:      entry.Amount,
+			Description: entry.Description,
+			OrderID:     entry.OrderID,
+			CreatedAt:   entry.CreatedAt,
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/payments"
+	"wearhouse/internal/types"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HandleWebhook handles webhook events from the payment provider named in
+// the URL. Every event is recorded before it is processed, so one the
+// provider delivers again is only acted on once.
+func (h *PaymentHandler) HandleWebhook(c *fiber.Ctx) error {
+	provider, err := h.providerByName(c.Params("provider"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Unknown payment provider")
+	}
+
+	payload := c.Body()
+	event, err := provider.ParseWebhook(payload, http.Header(c.GetReqHeaders()))
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook signature")
+		}
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook payload")
+	}
+
+	record := models.WebhookEvent{
+		ID:       uuid.New(),
+		Provider: provider.Name(),
+		EventID:  event.ID,
+		Type:     event.RawType,
+		Payload:  payload,
+		Status:   models.WebhookEventReceived,
+	}
+	if err := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		log.Printf("Error recording webhook event %s: %v", event.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record webhook event")
+	}
+	if err := database.DB.Where("provider = ? AND event_id = ?", provider.Name(), event.ID).First(&record).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to record webhook event")
+	}
+
+	switch record.Status {
+	case models.WebhookEventProcessed:
+		// Already handled, tell the provider to stop sending it
+		return c.SendStatus(fiber.StatusOK)
+	case models.WebhookEventProcessing:
+		return fiber.NewError(fiber.StatusConflict, "Webhook event is already being processed")
+	}
+
+	if err := h.processWebhookEvent(&record, event); err != nil {
+		if errors.Is(err, errWebhookEventClaimed) {
+			return fiber.NewError(fiber.StatusConflict, "Webhook event is already being processed")
+		}
+		log.Printf("Error processing webhook event %s (%s): %v", event.ID, event.RawType, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to process webhook event")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// ReplayWebhookEvent processes a recorded event again from its stored
+// payload, for events that failed or got stuck
+func (h *PaymentHandler) ReplayWebhookEvent(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid webhook event ID")
+	}
+
+	var record models.WebhookEvent
+	if err := database.DB.First(&record, "id = ?", id).Error; err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Webhook event not found")
+	}
+	if record.Status == models.WebhookEventProcessed {
+		return fiber.NewError(fiber.StatusConflict, "Webhook event was already processed")
+	}
+
+	provider, err := h.providerByName(record.Provider)
+	if err != nil {
+		return fiber.NewError(fiber.StatusConflict, "Payment provider is no longer available")
+	}
+	event, err := provider.DecodeEvent(record.Payload)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "Stored webhook payload is not a valid event")
+	}
+
+	// A stuck event is still marked processing, take it over
+	if err := database.DB.Model(&record).Update("status", models.WebhookEventFailed).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to replay webhook event")
+	}
+
+	if err := h.processWebhookEvent(&record, event); err != nil {
+		log.Printf("Error replaying webhook event %s (%s): %v", event.ID, event.RawType, err)
+		if errors.Is(err, errWebhookEventClaimed) {
+			return fiber.NewError(fiber.StatusConflict, "Webhook event is already being processed")
+		}
+	}
+
+	if err := database.DB.First(&record, "id = ?", id).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load webhook event")
+	}
+
+	return c.JSON(types.WebhookEventResponse{
+		ID:          record.ID,
+		Provider:    record.Provider,
+		EventID:     record.EventID,
+		Type:        record.Type,
+		Status:      string(record.Status),
+		Attempts:    record.Attempts,
+		Error:       record.Error,
+		ProcessedAt: record.ProcessedAt,
+		CreatedAt:   record.CreatedAt,
+	})
+}
+
+var errWebhookEventClaimed = errors.New("webhook event is being processed elsewhere")
+
+// processWebhookEvent claims a recorded event, acts on it and records the
+// outcome. Only one delivery can hold the claim at a time.
+func (h *PaymentHandler) processWebhookEvent(record *models.WebhookEvent, event *payments.Event) error {
+	result := database.DB.Model(&models.WebhookEvent{}).
+		Where("id = ? AND status IN ?", record.ID, []models.WebhookEventStatus{
+			models.WebhookEventReceived,
+			models.WebhookEventFailed,
+		}).
+		Updates(map[string]interface{}{
+			"status":   models.WebhookEventProcessing,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errWebhookEventClaimed
+	}
+
+	if err := h.dispatchEvent(record.Provider, event); err != nil {
+		database.DB.Model(record).Updates(map[string]interface{}{
+			"status": models.WebhookEventFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	now := time.Now()
+	return database.DB.Model(record).Updates(map[string]interface{}{
+		"status":       models.WebhookEventProcessed,
+		"error":        "",
+		"processed_at": now,
+	}).Error
+}
+
+// dispatchEvent acts on the event types we care about. Others are recorded
+// and otherwise ignored.
+func (h *PaymentHandler) dispatchEvent(provider string, event *payments.Event) error {
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		return h.handlePaymentSuccess(provider, event.Intent)
+	case payments.EventPaymentFailed:
+		return h.handlePaymentFailure(provider, event.Intent)
+	case payments.EventPaymentCanceled:
+		return h.handlePaymentCanceled(provider, event.Intent)
+	case payments.EventRefundUpdated:
+		return h.handleRefundUpdated(provider, event.Refunds)
+	case payments.EventDisputeUpdated:
+		return h.handleDispute(provider, event.Dispute)
+	}
+
+	return nil
+}
+
+// handlePaymentCanceled releases the checkout of an intent cancelled on the
+// provider's side. Intents we cancel ourselves are already marked cancelled
+// and have been replaced, so they are left alone.
+func (h *PaymentHandler) handlePaymentCanceled(provider string, intent *payments.Intent) error {
+	payment, err := h.findPayment(provider, intent.ID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// Wait for a payment intent request replacing this one to finish
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&models.Checkout{}, "id = ?", payment.CheckoutID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(payment, "id = ?", payment.ID).Error; err != nil {
+			return err
+		}
+		if payment.Status != string(types.PaymentStatusPending) {
+			return nil
+		}
+
+		if err := tx.Model(payment).Update("status", types.PaymentStatusCancelled).Error; err != nil {
+			return err
+		}
+		return releaseCheckout(tx, payment.CheckoutID, "Payment cancelled")
+	})
+}
+
+// handleDispute keeps our copy of a chargeback in step with the provider
+func (h *PaymentHandler) handleDispute(provider string, dispute *payments.Dispute) error {
+	payment, err := h.findPayment(provider, dispute.IntentID)
+	if err != nil {
+		return err
+	}
+
+	record := models.Dispute{
+		PaymentID:  payment.ID,
+		ProviderID: dispute.ID,
+		Amount:     dispute.Amount,
+		Reason:     dispute.Reason,
+		Status:     dispute.Status,
+	}
+	if dispute.Closed {
+		now := time.Now()
+		record.ClosedAt = &now
+	}
+
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount_cents", "amount_currency", "reason", "status", "closed_at", "updated_at"}),
+	}).Create(&record).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Dispute %s on payment %s is %s (%s)", dispute.ID, payment.ID, dispute.Status, dispute.Reason)
+	return nil
+}
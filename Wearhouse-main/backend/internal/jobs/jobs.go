@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Start launches the periodic background jobs. They run for the lifetime
+// of the process, and share the server's payment handler.
+func Start(config *configs.Config, paymentHandler *handlers.PaymentHandler) {
+	jobs := []job{
+		{name: "expire offers", interval: time.Minute, run: handlers.ExpireOffers},
+		{name: "release expired reservations", interval: time.Minute, run: func() error {
+			return handlers.ReleaseExpiredReservations(paymentHandler)
+		}},
+		{name: "send meetup reminders", interval: time.Minute, run: func() error {
+			return handlers.SendMeetupReminders(config)
+		}},
+		{name: "delete expired idempotency keys", interval: time.Hour, run: middleware.DeleteExpiredIdempotencyKeys},
+		{name: "poll shipment tracking", interval: 30 * time.Minute, run: func() error {
+			return handlers.PollShipments(config)
+		}},
+		{name: "pay out sellers", interval: config.PayoutInterval, run: func() error {
+			return handlers.PayOutSellers(config)
+		}},
+	}
+
+	for _, j := range jobs {
+		go runEvery(j)
+	}
+}
+
+func runEvery(j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := j.run(); err != nil {
+			log.Printf("Error running job %q: %v", j.name, err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"strings"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
 	"wearhouse/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -58,3 +60,24 @@ func AuthMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// AdminMiddleware only lets admins through. It must run after AuthMiddleware.
+// The flag is read from the database so revoking it takes effect immediately.
+func AdminMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*utils.JWTClaims)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		var user models.User
+		if err := database.DB.Select("is_admin").First(&user, "id = ?", claims.UserID).Error; err != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, "User not found")
+		}
+		if !user.IsAdmin {
+			return fiber.NewError(fiber.StatusForbidden, "Admin access required")
+		}
+
+		return c.Next()
+	}
+}
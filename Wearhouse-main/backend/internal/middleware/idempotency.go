@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+	"wearhouse/internal/database"
+	"wearhouse/internal/models"
+	"wearhouse/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyLockTimeout is how long a request holds its key. A retry after
+// that takes the key over, in case the request died before it could answer.
+const idempotencyLockTimeout = 2 * time.Minute
+
+// IdempotencyMiddleware makes a POST safe to retry when the client sends an
+// Idempotency-Key header. The first response for a key is kept for
+// retention and replayed for exact retries; reusing the key for a different
+// request is refused. It must run after AuthMiddleware, keys are per user.
+func IdempotencyMiddleware(retention time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("Idempotency-Key")
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return fiber.NewError(fiber.StatusBadRequest, "Idempotency-Key is too long")
+		}
+
+		claims, ok := c.Locals("user").(*utils.JWTClaims)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+		hash.Write(c.Body())
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		// Forget keys that have outlived the retention window. Postgres keeps
+		// microseconds, so the lock time is cut to match what is read back.
+		now := time.Now().Truncate(time.Microsecond)
+		if err := database.DB.Where("user_id = ? AND key = ? AND expires_at < ?", claims.UserID, key, now).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to check idempotency key")
+		}
+
+		// Claim the key. Only one request can, concurrent retries see it taken.
+		record := models.IdempotencyKey{
+			ID:          uuid.New(),
+			UserID:      claims.UserID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			LockedAt:    now,
+			ExpiresAt:   now.Add(retention),
+		}
+		result := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save idempotency key")
+		}
+
+		if result.RowsAffected == 0 {
+			var existing models.IdempotencyKey
+			if err := database.DB.Where("user_id = ? AND key = ?", claims.UserID, key).First(&existing).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to check idempotency key")
+			}
+			if existing.Fingerprint != fingerprint {
+				return fiber.NewError(fiber.StatusConflict, "Idempotency-Key was already used for a different request")
+			}
+			if existing.Completed {
+				c.Set("Idempotent-Replayed", "true")
+				c.Set(fiber.HeaderContentType, existing.ContentType)
+				return c.Status(existing.StatusCode).Send(existing.Response)
+			}
+
+			// Take over a key whose request has been running for too long
+			takeover := database.DB.Model(&models.IdempotencyKey{}).
+				Where("id = ? AND completed = ? AND locked_at < ?", existing.ID, false, now.Add(-idempotencyLockTimeout)).
+				Update("locked_at", now)
+			if takeover.Error != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Failed to check idempotency key")
+			}
+			if takeover.RowsAffected == 0 {
+				return fiber.NewError(fiber.StatusConflict, "A request with this Idempotency-Key is still in progress")
+			}
+			record = existing
+			record.LockedAt = now
+		}
+
+		// Only the request holding the key may release it or save its response
+		owned := database.DB.Model(&models.IdempotencyKey{}).
+			Where("id = ? AND locked_at = ?", record.ID, record.LockedAt).
+			Session(&gorm.Session{})
+
+		// Render errors here so the response that is kept is the one sent
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				owned.Delete(&models.IdempotencyKey{})
+				return handlerErr
+			}
+		}
+
+		// Server errors may go away, let the client retry them for real
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			if err := owned.Delete(&models.IdempotencyKey{}).Error; err != nil {
+				log.Printf("Error releasing idempotency key %s: %v", key, err)
+			}
+			return nil
+		}
+
+		if err := owned.Updates(map[string]interface{}{
+			"completed":    true,
+			"status_code":  status,
+			"content_type": string(c.Response().Header.ContentType()),
+			"response":     c.Response().Body(),
+		}).Error; err != nil {
+			log.Printf("Error saving response for idempotency key %s: %v", key, err)
+		}
+
+		return nil
+	}
+}
+
+// DeleteExpiredIdempotencyKeys forgets the responses kept past their
+// retention window
+func DeleteExpiredIdempotencyKeys() error {
+	return database.DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{}).Error
+}
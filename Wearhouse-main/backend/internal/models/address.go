@@ -0,0 +1,86 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Provinces lists the Canadian province and territory codes we accept
+var Provinces = []string{"AB", "BC", "MB", "NB", "NL", "NS", "NT", "NU", "ON", "PE", "QC", "SK", "YT"}
+
+// Canada Post never uses D, F, I, O, Q or U, and W and Z can't start a code
+var postalCodePattern = regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY][0-9][ABCEGHJ-NPRSTV-Z] ?[0-9][ABCEGHJ-NPRSTV-Z][0-9]$`)
+
+// PostalAddress holds the parts of a Canadian mailing address. Orders embed
+// a copy so editing the address book doesn't rewrite past orders.
+type PostalAddress struct {
+	Name       string `gorm:"type:varchar(200)"`
+	Street     string `gorm:"type:varchar(255)"`
+	City       string `gorm:"type:varchar(100)"`
+	Province   string `gorm:"type:varchar(2)"`
+	PostalCode string `gorm:"type:varchar(7)"`
+	Phone      string `gorm:"type:varchar(20)"`
+}
+
+// String formats the address on a single line
+func (a PostalAddress) String() string {
+	parts := []string{a.Name, a.Street, a.City, a.Province + " " + a.PostalCode}
+	return strings.Join(parts, ", ")
+}
+
+// Address is an entry in a user's address book
+type Address struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID        uuid.UUID     `gorm:"type:uuid;not null;index"`
+	User          User          `gorm:"foreignKey:UserID"`
+	Label         string        `gorm:"type:varchar(50)"` // e.g. "Home", "Residence"
+	PostalAddress PostalAddress `gorm:"embedded"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"` // Past orders keep pointing at deleted addresses
+}
+
+// BeforeCreate is called before inserting a new address
+func (a *Address) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// NormalizePostalCode returns a postal code in the "A1A 1A1" form
+func NormalizePostalCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	if len(code) == 6 {
+		code = code[:3] + " " + code[3:]
+	}
+	return code
+}
+
+// IsValidPostalCode reports whether code is a well-formed Canadian postal code
+func IsValidPostalCode(code string) bool {
+	return postalCodePattern.MatchString(NormalizePostalCode(code))
+}
+
+// NormalizePhone returns the ten digits of a North American phone number, or
+// "" if phone isn't one
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+	if len(number) == 11 && number[0] == '1' {
+		number = number[1:]
+	}
+	if len(number) != 10 {
+		return ""
+	}
+	return number
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,6 +13,8 @@ type Cart struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
 	UserID    uuid.UUID      `gorm:"type:uuid;not null"`
 	Items     []CartItem     `gorm:"foreignKey:CartID"`
+	CouponID  *uuid.UUID     `gorm:"type:uuid"` // The promo code the buyer applied
+	Coupon    *Coupon        `gorm:"foreignKey:CouponID"`
 	CreatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -19,15 +22,29 @@ type Cart struct {
 
 // CartItem represents an item in a user's cart
 type CartItem struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	CartID    uuid.UUID      `gorm:"type:uuid;not null"`
-	Cart      Cart           `gorm:"foreignKey:CartID"`
-	ProductID uuid.UUID      `gorm:"type:uuid;not null"`
-	Product   Product        `gorm:"foreignKey:ProductID"`
-	Quantity  int            `gorm:"not null;default:1"`
-	CreatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CartID    uuid.UUID  `gorm:"type:uuid;not null"`
+	Cart      Cart       `gorm:"foreignKey:CartID"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null"`
+	Product   Product    `gorm:"foreignKey:ProductID"`
+	Quantity  int        `gorm:"not null;default:1"`
+	OfferID   *uuid.UUID `gorm:"type:uuid"` // Set when the item was added by an accepted offer
+	Offer     *Offer     `gorm:"foreignKey:OfferID"`
+	// QuotedPrice is the unit price the buyer last saw, used to detect
+	// repricing before checkout
+	QuotedPrice money.Money    `gorm:"embedded;embeddedPrefix:quoted_price_"`
+	CreatedAt   time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// UnitPrice returns the price the buyer pays for one unit of the item: the
+// agreed offer amount if there is one, otherwise the listing price
+func (ci *CartItem) UnitPrice() money.Money {
+	if ci.Offer != nil && ci.Offer.Status == OfferStatusAccepted {
+		return ci.Offer.Amount
+	}
+	return ci.Product.Price
 }
 
 // BeforeCreate is called before creating a new cart
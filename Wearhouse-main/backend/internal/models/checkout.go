@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CheckoutStatus string
+
+const (
+	CheckoutStatusPending   CheckoutStatus = "pending"
+	CheckoutStatusPaid      CheckoutStatus = "paid"
+	CheckoutStatusCancelled CheckoutStatus = "cancelled"
+)
+
+// Checkout groups the per-seller orders created from a single cart so the
+// buyer pays for all of them at once
+type Checkout struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID        uuid.UUID      `gorm:"type:uuid;not null;index"`
+	User          User           `gorm:"foreignKey:UserID"`
+	Orders        []Order        `gorm:"foreignKey:CheckoutID"`
+	Status        CheckoutStatus `gorm:"type:varchar(20);not null;default:'pending'"`
+	Total         money.Money    `gorm:"embedded;embeddedPrefix:total_"`
+	PaymentMethod string         `gorm:"type:varchar(50);not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// BeforeCreate is called before inserting a new checkout
+func (c *Checkout) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
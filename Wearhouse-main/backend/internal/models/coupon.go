@@ -0,0 +1,119 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CouponType is how a coupon works out its discount
+type CouponType string
+
+const (
+	CouponPercent CouponType = "percent" // A share of the eligible items
+	CouponFixed   CouponType = "fixed"   // A set amount off the eligible items
+)
+
+// Coupon is a promo code buyers apply to their cart. It takes money off the
+// items it covers; empty scopes cover anything. Discounts are paid for by
+// the platform, so sellers are still paid their full price.
+type Coupon struct {
+	ID             uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Code           string      `gorm:"type:varchar(50);not null;uniqueIndex"` // Upper case
+	Description    string      `gorm:"type:text"`                             // Shown to the buyer
+	Type           CouponType  `gorm:"type:varchar(10);not null"`
+	PercentBPS     int         `gorm:"not null;default:0"`                    // Basis points off, for percent coupons
+	Amount         money.Money `gorm:"embedded;embeddedPrefix:amount_"`       // Taken off, for fixed coupons
+	MaxDiscount    money.Money `gorm:"embedded;embeddedPrefix:max_discount_"` // Cap on percent coupons, zero for none
+	MinSpend       money.Money `gorm:"embedded;embeddedPrefix:min_spend_"`    // On the items the coupon covers
+	University     string      `gorm:"type:varchar(255)"`                     // The buyer's university
+	Category       string      `gorm:"type:varchar(50)"`
+	SellerID       *uuid.UUID  `gorm:"type:uuid;index"`
+	MaxUses        int         `gorm:"not null;default:0"` // By everyone, 0 for no limit
+	MaxUsesPerUser int         `gorm:"not null;default:0"` // 0 for no limit
+	StartsAt       *time.Time
+	EndsAt         *time.Time
+	IsActive       bool       `gorm:"not null;default:true"` // Coupons are deactivated rather than deleted
+	CreatedByID    *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CouponRedemption is one use of a coupon, by the checkout it took money
+// off. It is deleted if the checkout is cancelled before it is paid, so the
+// buyer can use the coupon again.
+type CouponRedemption struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CouponID   uuid.UUID   `gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID   `gorm:"type:uuid;not null;index"`
+	CheckoutID uuid.UUID   `gorm:"type:uuid;not null;uniqueIndex"`
+	Discount   money.Money `gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt  time.Time
+}
+
+// IsRedeemableAt reports whether the coupon is active and within its
+// validity window at now
+func (c *Coupon) IsRedeemableAt(now time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return false
+	}
+	return c.EndsAt == nil || now.Before(*c.EndsAt)
+}
+
+// Covers reports whether the coupon takes money off product. Only listings
+// for sale have a price to take it off.
+func (c *Coupon) Covers(product *Product) bool {
+	if product.ListingType != Sale {
+		return false
+	}
+	if c.Category != "" && !strings.EqualFold(c.Category, product.Category) {
+		return false
+	}
+	return c.SellerID == nil || *c.SellerID == product.UserID
+}
+
+// Discount is what the coupon takes off items worth eligible, rounded to
+// the nearest cent and never more than the items are worth
+func (c *Coupon) Discount(eligible money.Money) money.Money {
+	var discount int64
+	switch c.Type {
+	case CouponPercent:
+		discount = (eligible.Cents*int64(c.PercentBPS) + 5000) / 10000
+		if c.MaxDiscount.Cents > 0 && discount > c.MaxDiscount.Cents {
+			discount = c.MaxDiscount.Cents
+		}
+	case CouponFixed:
+		discount = c.Amount.Cents
+	}
+	if discount > eligible.Cents {
+		discount = eligible.Cents
+	}
+	return money.Money{Cents: discount, Currency: eligible.Currency}
+}
+
+// NormalizeCouponCode returns the form codes are stored and looked up in
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// BeforeCreate is called before inserting a new coupon
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is called before inserting a new coupon redemption
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+	"wearhouse/internal/money"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		coupon   Coupon
+		eligible int64
+		want     int64
+	}{
+		{"percent", Coupon{Type: CouponPercent, PercentBPS: 1000}, 2500, 250},
+		{"percent rounds half up", Coupon{Type: CouponPercent, PercentBPS: 1000}, 2505, 251},
+		{"percent rounds down", Coupon{Type: CouponPercent, PercentBPS: 1000}, 2504, 250},
+		{"percent capped", Coupon{Type: CouponPercent, PercentBPS: 5000, MaxDiscount: money.Cents(1000)}, 5000, 1000},
+		{"percent under the cap", Coupon{Type: CouponPercent, PercentBPS: 5000, MaxDiscount: money.Cents(1000)}, 1500, 750},
+		{"percent of everything", Coupon{Type: CouponPercent, PercentBPS: 10000}, 1999, 1999},
+		{"fixed", Coupon{Type: CouponFixed, Amount: money.Cents(500)}, 2500, 500},
+		{"fixed never more than the items", Coupon{Type: CouponFixed, Amount: money.Cents(500)}, 300, 300},
+		{"nothing eligible", Coupon{Type: CouponFixed, Amount: money.Cents(500)}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.coupon.Discount(money.Cents(tt.eligible))
+			if got.Cents != tt.want {
+				t.Errorf("Discount(%d) = %d cents, want %d", tt.eligible, got.Cents, tt.want)
+			}
+			if got.Currency != money.Currency {
+				t.Errorf("Discount(%d) currency = %q, want %q", tt.eligible, got.Currency, money.Currency)
+			}
+		})
+	}
+}
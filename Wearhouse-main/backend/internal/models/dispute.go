@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Dispute is a chargeback the buyer opened with their bank against a
+// payment. Status is the provider's own dispute status.
+type Dispute struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PaymentID  uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Payment    Payment     `gorm:"foreignKey:PaymentID"`
+	ProviderID string      `gorm:"type:varchar(255);not null;unique"` // The provider's ID for the dispute
+	Amount     money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	Reason     string      `gorm:"type:varchar(50)"`
+	Status     string      `gorm:"type:varchar(30);not null"`
+	ClosedAt   *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// BeforeCreate is called before inserting a new dispute
+func (d *Dispute) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
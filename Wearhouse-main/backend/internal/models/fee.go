@@ -0,0 +1,120 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SellerTier sets which fee rules apply to a seller's listings
+type SellerTier string
+
+const (
+	SellerTierStandard SellerTier = "standard"
+	SellerTierPro      SellerTier = "pro"
+)
+
+// FeeSchedule is one version of the platform's fee rules. Schedules are
+// never changed once created: a new version takes over from its
+// EffectiveFrom, and orders keep the schedule and rules they were charged
+// under so their fees can always be worked out again.
+type FeeSchedule struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Version       int        `gorm:"not null;uniqueIndex"`
+	EffectiveFrom time.Time  `gorm:"not null;index"`
+	Note          string     `gorm:"type:text"`
+	CreatedByID   *uuid.UUID `gorm:"type:uuid"`
+	Rules         []FeeRule  `gorm:"foreignKey:ScheduleID"`
+	CreatedAt     time.Time
+}
+
+// FeeRule charges a fee on the order items it matches. Empty conditions
+// match anything, and the first matching rule by Position applies.
+type FeeRule struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ScheduleID  uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Position    int         `gorm:"not null"`
+	Name        string      `gorm:"type:varchar(100);not null"` // Shown to the buyer
+	ListingType ListingType `gorm:"type:varchar(10)"`
+	Category    string      `gorm:"type:varchar(50)"`
+	SellerTier  SellerTier  `gorm:"type:varchar(20)"`
+	MinPrice    money.Money `gorm:"embedded;embeddedPrefix:min_price_"` // Inclusive
+	MaxPrice    money.Money `gorm:"embedded;embeddedPrefix:max_price_"` // Exclusive, zero for no upper bound
+	PercentBPS  int         `gorm:"not null;default:0"`                 // Basis points of the item price
+	Fixed       money.Money `gorm:"embedded;embeddedPrefix:fixed_"`
+	MinFee      money.Money `gorm:"embedded;embeddedPrefix:min_fee_"`
+	MaxFee      money.Money `gorm:"embedded;embeddedPrefix:max_fee_"` // Cap, zero for none
+	CreatedAt   time.Time
+}
+
+// FeeItem is what fee rules look at on an order item
+type FeeItem struct {
+	ListingType ListingType
+	Category    string
+	SellerTier  SellerTier
+	Price       money.Money
+}
+
+// Matches reports whether the rule applies to item
+func (r *FeeRule) Matches(item FeeItem) bool {
+	if r.ListingType != "" && r.ListingType != item.ListingType {
+		return false
+	}
+	if r.Category != "" && !strings.EqualFold(r.Category, item.Category) {
+		return false
+	}
+	if r.SellerTier != "" && r.SellerTier != item.SellerTier {
+		return false
+	}
+	if item.Price.Cents < r.MinPrice.Cents {
+		return false
+	}
+	return r.MaxPrice.IsZero() || item.Price.Cents < r.MaxPrice.Cents
+}
+
+// Fee is the rule's fee on an item of the given price, rounded to the
+// nearest cent
+func (r *FeeRule) Fee(price money.Money) money.Money {
+	fee := (price.Cents*int64(r.PercentBPS)+5000)/10000 + r.Fixed.Cents
+	if fee < r.MinFee.Cents {
+		fee = r.MinFee.Cents
+	}
+	if !r.MaxFee.IsZero() && fee > r.MaxFee.Cents {
+		fee = r.MaxFee.Cents
+	}
+	return money.Cents(fee)
+}
+
+// FeeFor returns the rule that applies to item and its fee, or nil if none
+// does. Free and trade listings never pay a fee. The rules must be sorted by
+// Position.
+func (s *FeeSchedule) FeeFor(item FeeItem) (*FeeRule, money.Money) {
+	if item.ListingType == Free || item.ListingType == Trade {
+		return nil, money.Cents(0)
+	}
+	for i := range s.Rules {
+		if s.Rules[i].Matches(item) {
+			return &s.Rules[i], s.Rules[i].Fee(item.Price)
+		}
+	}
+	return nil, money.Cents(0)
+}
+
+// BeforeCreate is called before inserting a new fee schedule
+func (s *FeeSchedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is called before inserting a new fee rule
+func (r *FeeRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
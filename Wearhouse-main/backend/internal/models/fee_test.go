@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+	"wearhouse/internal/money"
+)
+
+func TestFeeRuleFee(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  FeeRule
+		price int64
+		want  int64
+	}{
+		{"percent", FeeRule{PercentBPS: 500}, 2500, 125},
+		{"percent rounds half up", FeeRule{PercentBPS: 500}, 2510, 126},
+		{"percent rounds down", FeeRule{PercentBPS: 500}, 2509, 125},
+		{"percent and fixed", FeeRule{PercentBPS: 500, Fixed: money.Cents(30)}, 2500, 155},
+		{"fixed only", FeeRule{Fixed: money.Cents(100)}, 2500, 100},
+		{"raised to the minimum", FeeRule{PercentBPS: 500, MinFee: money.Cents(50)}, 500, 50},
+		{"capped at the maximum", FeeRule{PercentBPS: 500, MaxFee: money.Cents(1000)}, 50000, 1000},
+		{"no cap when the maximum is zero", FeeRule{PercentBPS: 500}, 50000, 2500},
+		{"free item pays the minimum", FeeRule{PercentBPS: 500, MinFee: money.Cents(50)}, 0, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.Fee(money.Cents(tt.price))
+			if got.Cents != tt.want {
+				t.Errorf("Fee(%d) = %d cents, want %d", tt.price, got.Cents, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeeScheduleFeeFor(t *testing.T) {
+	schedule := FeeSchedule{Rules: []FeeRule{
+		{Position: 1, Name: "pro sellers", SellerTier: SellerTierPro, PercentBPS: 300},
+		{Position: 2, Name: "textbooks", Category: "Textbooks", PercentBPS: 200, MaxFee: money.Cents(500)},
+		{Position: 3, Name: "under $10", MaxPrice: money.Cents(1000), Fixed: money.Cents(50)},
+		{Position: 4, Name: "standard", MinPrice: money.Cents(1000), PercentBPS: 500},
+	}}
+
+	tests := []struct {
+		name     string
+		item     FeeItem
+		wantRule string // Empty for no rule
+		want     int64
+	}{
+		{"first match wins", FeeItem{ListingType: Sale, Category: "Textbooks", SellerTier: SellerTierPro, Price: money.Cents(2000)}, "pro sellers", 60},
+		{"category matches case-insensitively", FeeItem{ListingType: Sale, Category: "textbooks", SellerTier: SellerTierStandard, Price: money.Cents(40000)}, "textbooks", 500},
+		{"below the maximum price", FeeItem{ListingType: Sale, SellerTier: SellerTierStandard, Price: money.Cents(999)}, "under $10", 50},
+		{"maximum price is exclusive", FeeItem{ListingType: Sale, SellerTier: SellerTierStandard, Price: money.Cents(1000)}, "standard", 50},
+		{"minimum price is inclusive", FeeItem{ListingType: Sale, SellerTier: SellerTierStandard, Price: money.Cents(2000)}, "standard", 100},
+		{"free listings pay nothing", FeeItem{ListingType: Free, SellerTier: SellerTierPro}, "", 0},
+		{"trades pay nothing", FeeItem{ListingType: Trade, SellerTier: SellerTierPro, Price: money.Cents(2000)}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, fee := schedule.FeeFor(tt.item)
+			var name string
+			if rule != nil {
+				name = rule.Name
+			}
+			if name != tt.wantRule {
+				t.Errorf("FeeFor matched rule %q, want %q", name, tt.wantRule)
+			}
+			if fee.Cents != tt.want {
+				t.Errorf("FeeFor fee = %d cents, want %d", fee.Cents, tt.want)
+			}
+		})
+	}
+
+	empty := FeeSchedule{}
+	if rule, fee := empty.FeeFor(FeeItem{ListingType: Sale, Price: money.Cents(2000)}); rule != nil || !fee.IsZero() {
+		t.Errorf("empty schedule charged %d cents under %v", fee.Cents, rule)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header, so a retry gets the same response instead of
+// repeating the request
+type IdempotencyKey struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key         string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Fingerprint string    `gorm:"type:varchar(64);not null"` // SHA-256 of the method, path and body
+	Completed   bool      `gorm:"default:false"`             // False while the first request is still running
+	LockedAt    time.Time `gorm:"not null;default:now()"`    // When the running request claimed the key
+	StatusCode  int
+	ContentType string    `gorm:"type:varchar(255)"`
+	Response    []byte    `gorm:"type:bytea"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LedgerAccount is where money sits on the platform's books
+type LedgerAccount string
+
+const (
+	LedgerBuyerCharges    LedgerAccount = "buyer_charges"    // Money buyers paid through a payment provider
+	LedgerPlatformFees    LedgerAccount = "platform_fees"    // Fees buyers pay us on their orders
+	LedgerSalesTax        LedgerAccount = "sales_tax"        // Collected from buyers, owed to the government
+	LedgerPromotions      LedgerAccount = "promotions"       // Coupon discounts we pay for
+	LedgerSellerPending   LedgerAccount = "seller_pending"   // Held in escrow until the order is delivered
+	LedgerSellerAvailable LedgerAccount = "seller_available" // Released to the seller, waiting to be paid out
+	LedgerPayouts         LedgerAccount = "payouts"          // Sent to sellers' bank accounts
+	LedgerStoreCredit     LedgerAccount = "store_credit"     // Kept in users' wallets to spend on purchases
+)
+
+// LedgerEntry is one side of a double-entry posting. The entries of a
+// posting share a TransactionID and sum to zero. Amounts are credits, so the
+// balance of an account is the sum of its entries: buyer charges are
+// negative as the money came from outside, seller accounts are positive.
+type LedgerEntry struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TransactionID uuid.UUID     `gorm:"type:uuid;not null;index"`
+	Account       LedgerAccount `gorm:"type:varchar(30);not null;index:idx_ledger_entries_account_user"`
+	UserID        *uuid.UUID    `gorm:"type:uuid;index:idx_ledger_entries_account_user"` // The seller on seller accounts, the owner of store credit
+	OrderID       *uuid.UUID    `gorm:"type:uuid;index"`
+	PayoutID      *uuid.UUID    `gorm:"type:uuid;index"`
+	Amount        money.Money   `gorm:"embedded;embeddedPrefix:amount_"`
+	Description   string        `gorm:"type:text"`
+	CreatedAt     time.Time
+}
+
+// BeforeCreate is called before inserting a new ledger entry
+func (e *LedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
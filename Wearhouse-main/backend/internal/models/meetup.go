@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MeetupSpot is a public place on campus where buyers and sellers can
+// safely hand items over. Spots are managed by admins.
+type MeetupSpot struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Name        string    `gorm:"type:varchar(100);not null"`
+	Description string    `gorm:"type:text"`
+	IsActive    bool      `gorm:"default:true"` // Inactive spots can't be proposed but stay on past orders
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BeforeCreate is called before inserting a new meetup spot
+func (s *MeetupSpot) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+type MeetupProposalStatus string
+
+const (
+	MeetupProposalOpen       MeetupProposalStatus = "proposed"
+	MeetupProposalAccepted   MeetupProposalStatus = "accepted"
+	MeetupProposalDeclined   MeetupProposalStatus = "declined"
+	MeetupProposalSuperseded MeetupProposalStatus = "superseded"
+)
+
+// MeetupProposal is a time and place one party suggests for a meetup. The
+// other party accepts one or proposes their own.
+type MeetupProposal struct {
+	ID           uuid.UUID            `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrderID      uuid.UUID            `gorm:"type:uuid;not null;index"`
+	SpotID       uuid.UUID            `gorm:"type:uuid;not null"`
+	Spot         MeetupSpot           `gorm:"foreignKey:SpotID"`
+	StartsAt     time.Time            `gorm:"not null"`
+	ProposedByID uuid.UUID            `gorm:"type:uuid;not null"`
+	ProposedBy   OrderActor           `gorm:"type:varchar(20);not null"`
+	Status       MeetupProposalStatus `gorm:"type:varchar(20);not null;default:'proposed'"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// BeforeCreate is called before inserting a new meetup proposal
+func (p *MeetupProposal) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
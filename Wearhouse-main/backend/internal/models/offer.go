@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OfferStatus string
+
+const (
+	OfferStatusPending   OfferStatus = "pending"   // waiting on the seller
+	OfferStatusCountered OfferStatus = "countered" // waiting on the buyer
+	OfferStatusAccepted  OfferStatus = "accepted"
+	OfferStatusDeclined  OfferStatus = "declined"
+	OfferStatusWithdrawn OfferStatus = "withdrawn"
+	OfferStatusExpired   OfferStatus = "expired"
+	OfferStatusCompleted OfferStatus = "completed" // an order was placed at the agreed price
+)
+
+// Offer represents a buyer's price negotiation on a SALE listing
+type Offer struct {
+	ID        uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ProductID uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Product   Product     `gorm:"foreignKey:ProductID"`
+	BuyerID   uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Buyer     User        `gorm:"foreignKey:BuyerID"`
+	SellerID  uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Seller    User        `gorm:"foreignKey:SellerID"`
+	Amount    money.Money `gorm:"embedded;embeddedPrefix:amount_"` // Currently proposed price
+	Message   string      `gorm:"type:text"`
+	Status    OfferStatus `gorm:"type:varchar(20);not null;default:'pending'"`
+	// ExpiresAt bounds how long the other party has to respond while the
+	// offer is open, and how long the buyer has to check out once accepted.
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsOpen reports whether the offer is still being negotiated
+func (o *Offer) IsOpen() bool {
+	return o.Status == OfferStatusPending || o.Status == OfferStatusCountered
+}
+
+// BeforeCreate is called before inserting a new offer
+func (o *Offer) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,38 +13,184 @@ import (
 type OrderStatus string
 
 const (
-	OrderStatusPending   OrderStatus = "pending"
-	OrderStatusPaid      OrderStatus = "paid"
-	OrderStatusShipped   OrderStatus = "shipped"
-	OrderStatusDelivered OrderStatus = "delivered"
-	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusPending         OrderStatus = "pending"
+	OrderStatusAwaitingHandoff OrderStatus = "awaiting_handoff" // Cash orders, paid when handed over at the meetup
+	OrderStatusPaid            OrderStatus = "paid"
+	OrderStatusShipped         OrderStatus = "shipped"
+	OrderStatusReady           OrderStatus = "ready_for_pickup"
+	OrderStatusDelivered       OrderStatus = "delivered"
+	OrderStatusCancelled       OrderStatus = "cancelled"
 )
 
+// OrderActor is the role of whoever moves an order to a new status
+type OrderActor string
+
+const (
+	ActorBuyer  OrderActor = "buyer"
+	ActorSeller OrderActor = "seller"
+	ActorSystem OrderActor = "system" // payments, timeouts and other automatic changes
+)
+
+// orderTransitions lists, for every status, the statuses an order may move
+// to next and who may make that move
+var orderTransitions = map[OrderStatus]map[OrderStatus][]OrderActor{
+	OrderStatusPending: {
+		OrderStatusPaid:      {ActorSystem},
+		OrderStatusCancelled: {ActorBuyer, ActorSeller, ActorSystem},
+	},
+	// Paid in cash or e-Transfer when the item is handed over, after which
+	// the order is delivered straight away
+	OrderStatusAwaitingHandoff: {
+		OrderStatusPaid:      {ActorSystem},
+		OrderStatusCancelled: {ActorBuyer, ActorSeller, ActorSystem},
+	},
+	// Cancelling a paid order refunds the buyer
+	OrderStatusPaid: {
+		OrderStatusShipped:   {ActorSeller},
+		OrderStatusReady:     {ActorSeller},
+		OrderStatusCancelled: {ActorBuyer, ActorSeller, ActorSystem},
+		OrderStatusDelivered: {ActorSystem}, // Handed over in person with the buyer's code
+	},
+	OrderStatusShipped: {
+		OrderStatusDelivered: {ActorBuyer, ActorSystem},
+	},
+	OrderStatusReady: {
+		OrderStatusDelivered: {ActorBuyer, ActorSystem},
+		OrderStatusCancelled: {ActorBuyer, ActorSeller, ActorSystem},
+	},
+}
+
+var (
+	ErrInvalidTransition   = errors.New("invalid order status transition")
+	ErrTransitionForbidden = errors.New("order status transition not allowed for this actor")
+)
+
+// CheckTransition returns nil if actor may move an order from status s to
+// status to, ErrInvalidTransition if nobody may and ErrTransitionForbidden
+// if only someone else may
+func (s OrderStatus) CheckTransition(to OrderStatus, actor OrderActor) error {
+	allowed, ok := orderTransitions[s][to]
+	if !ok {
+		return ErrInvalidTransition
+	}
+	for _, a := range allowed {
+		if a == actor {
+			return nil
+		}
+	}
+	return ErrTransitionForbidden
+}
+
 // OrderItem represents a single item in an order
 type OrderItem struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	OrderID   uuid.UUID `gorm:"type:uuid;not null"`
-	ProductID uuid.UUID `gorm:"type:uuid;not null"`
-	Product   Product   `gorm:"foreignKey:ProductID"`
-	Quantity  int       `gorm:"not null"`
-	Price     float64   `gorm:"not null"` // Price at time of order
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrderID   uuid.UUID   `gorm:"type:uuid;not null"`
+	ProductID uuid.UUID   `gorm:"type:uuid;not null"`
+	Product   Product     `gorm:"foreignKey:ProductID"`
+	Quantity  int         `gorm:"not null"`
+	Price     money.Money `gorm:"embedded;embeddedPrefix:price_"` // Price at time of order
+	OfferID   *uuid.UUID  `gorm:"type:uuid"`                      // Set when Price was negotiated through an offer
+	Fee       money.Money `gorm:"embedded;embeddedPrefix:fee_"`   // Platform fee the buyer pays on the item
+	FeeRuleID *uuid.UUID  `gorm:"type:uuid"`                      // The rule Fee was charged under
+	FeeRule   *FeeRule    `gorm:"foreignKey:FeeRuleID"`
+	Discount  money.Money `gorm:"embedded;embeddedPrefix:discount_"` // Taken off the item by the order's coupon
+	// The listing as it was at the time of order, so later edits or deletion
+	// don't change past orders
+	Title      string    `gorm:"type:varchar(255)"`
+	Brand      string    `gorm:"type:varchar(100)"`
+	Size       string    `gorm:"type:varchar(10)"`
+	Condition  string    `gorm:"type:varchar(50)"`
+	ImageURL   string    `gorm:"type:text"`
+	SellerID   uuid.UUID `gorm:"type:uuid"`
+	SellerName string    `gorm:"type:varchar(200)"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
-// Order represents a customer order
+// SnapshotProduct copies the details of product shown on orders. The
+// product's User must be loaded.
+func (item *OrderItem) SnapshotProduct(product *Product) {
+	item.ProductID = product.ID
+	item.Title = product.Title
+	item.Brand = product.Brand
+	item.Size = product.Size
+	item.Condition = product.Condition
+	item.ImageURL = ""
+	if len(product.Images) > 0 {
+		item.ImageURL = product.Images[0]
+	}
+	item.SellerID = product.UserID
+	item.SellerName = strings.TrimSpace(product.User.FirstName + " " + product.User.LastName)
+}
+
+type FulfilmentMethod string
+
+const (
+	FulfilmentShip   FulfilmentMethod = "ship"
+	FulfilmentMeetup FulfilmentMethod = "meetup" // Handed over in person on campus
+)
+
+// Order represents the part of a checkout sold by a single seller. Each
+// order moves through its own status and fulfilment independently.
 type Order struct {
-	ID            uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	UserID        uuid.UUID   `gorm:"type:uuid;not null"`
-	User          User        `gorm:"foreignKey:UserID"`
-	Items         []OrderItem `gorm:"foreignKey:OrderID"`
-	Status        OrderStatus `gorm:"type:varchar(20);not null;default:'pending'"`
-	Total         float64     `gorm:"not null"`
-	ShippingAddr  string      `gorm:"type:text;not null"`
-	BillingAddr   string      `gorm:"type:text;not null"`
-	PaymentMethod string      `gorm:"type:varchar(50);not null"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID               uuid.UUID            `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CheckoutID       uuid.UUID            `gorm:"type:uuid;index"`
+	UserID           uuid.UUID            `gorm:"type:uuid;not null"`
+	User             User                 `gorm:"foreignKey:UserID"`
+	SellerID         uuid.UUID            `gorm:"type:uuid;index"`
+	Seller           User                 `gorm:"foreignKey:SellerID"`
+	Items            []OrderItem          `gorm:"foreignKey:OrderID"`
+	StatusHistory    []OrderStatusHistory `gorm:"foreignKey:OrderID"`
+	Refunds          []Refund             `gorm:"foreignKey:OrderID"`
+	Shipment         *Shipment            `gorm:"foreignKey:OrderID"`
+	Status           OrderStatus          `gorm:"type:varchar(20);not null;default:'pending'"`
+	FulfilmentMethod FulfilmentMethod     `gorm:"type:varchar(20);not null;default:'ship'"`
+	Total            money.Money          `gorm:"embedded;embeddedPrefix:total_"` // What the buyer pays, fees included
+	Fee              money.Money          `gorm:"embedded;embeddedPrefix:fee_"`
+	FeeScheduleID    *uuid.UUID           `gorm:"type:uuid"` // The fee schedule in effect when the order was placed
+	Tax              money.Money          `gorm:"embedded;embeddedPrefix:tax_"`
+	TaxLines         []TaxLine            `gorm:"foreignKey:OrderID"`
+	CouponID         *uuid.UUID           `gorm:"type:uuid;index"`
+	Coupon           *Coupon              `gorm:"foreignKey:CouponID"`
+	Discount         money.Money          `gorm:"embedded;embeddedPrefix:discount_"` // Taken off the items by the coupon
+	Credit           money.Money          `gorm:"embedded;embeddedPrefix:credit_"`   // Paid from the buyer's wallet, the rest is charged online
+	RefundAsCredit   bool                 `gorm:"default:false"`                     // Refunded to the buyer's wallet rather than their card
+	ShippingAddrID   *uuid.UUID           `gorm:"type:uuid"`
+	ShippingAddress  PostalAddress        `gorm:"embedded;embeddedPrefix:shipping_"` // As it was at checkout
+	BillingAddrID    *uuid.UUID           `gorm:"type:uuid"`
+	BillingAddress   PostalAddress        `gorm:"embedded;embeddedPrefix:billing_"` // As it was at checkout
+	ShippingAddr     string               `gorm:"type:text;not null"`               // Formatted copy, the only address older orders have
+	BillingAddr      string               `gorm:"type:text;not null"`
+	PaymentMethod    string               `gorm:"type:varchar(50);not null"`
+	ReservedUntil    *time.Time           // Products are held until then while payment is pending
+	MeetupSpotID     *uuid.UUID           `gorm:"type:uuid"` // Agreed meetup, nil until a proposal is accepted
+	MeetupSpot       *MeetupSpot          `gorm:"foreignKey:MeetupSpotID"`
+	MeetupAt         *time.Time
+	MeetupRemindedAt *time.Time
+	MeetupProposals  []MeetupProposal `gorm:"foreignKey:OrderID"`
+	HandoffCode      string           `gorm:"type:varchar(6)"` // One-time code only the buyer sees
+	HandoffAttempts  int              `gorm:"default:0"`       // Failed tries since the last lockout
+	HandoffLockedAt  *time.Time       // Further tries are refused for a while after too many failures
+	HandedOverAt     *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// OrderStatusHistory records every status change of an order
+type OrderStatusHistory struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrderID    uuid.UUID   `gorm:"type:uuid;not null;index"`
+	FromStatus OrderStatus `gorm:"type:varchar(20)"` // Empty for the order being placed
+	ToStatus   OrderStatus `gorm:"type:varchar(20);not null"`
+	ActorID    *uuid.UUID  `gorm:"type:uuid"` // Nil for system changes
+	ActorRole  OrderActor  `gorm:"type:varchar(20);not null"`
+	Reason     string      `gorm:"type:text"`
+	CreatedAt  time.Time
+}
+
+// TableName keeps the history in a single, singular-named table
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
 }
 
 // BeforeCreate is called before inserting a new order
@@ -59,3 +208,11 @@ func (oi *OrderItem) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// BeforeCreate is called before inserting a new status history entry
+func (h *OrderStatusHistory) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  OrderStatus
+		to    OrderStatus
+		actor OrderActor
+		want  error
+	}{
+		{"payment settles a pending order", OrderStatusPending, OrderStatusPaid, ActorSystem, nil},
+		{"buyer can't mark an order paid", OrderStatusPending, OrderStatusPaid, ActorBuyer, ErrTransitionForbidden},
+		{"buyer cancels before paying", OrderStatusPending, OrderStatusCancelled, ActorBuyer, nil},
+		{"pending order can't ship", OrderStatusPending, OrderStatusShipped, ActorSeller, ErrInvalidTransition},
+		{"cash is paid at the handoff", OrderStatusAwaitingHandoff, OrderStatusPaid, ActorSystem, nil},
+		{"seller ships a paid order", OrderStatusPaid, OrderStatusShipped, ActorSeller, nil},
+		{"buyer can't ship", OrderStatusPaid, OrderStatusShipped, ActorBuyer, ErrTransitionForbidden},
+		{"seller readies a paid order", OrderStatusPaid, OrderStatusReady, ActorSeller, nil},
+		{"handoff code delivers a paid order", OrderStatusPaid, OrderStatusDelivered, ActorSystem, nil},
+		{"seller can't deliver a paid order", OrderStatusPaid, OrderStatusDelivered, ActorSeller, ErrTransitionForbidden},
+		{"buyer confirms a shipment arrived", OrderStatusShipped, OrderStatusDelivered, ActorBuyer, nil},
+		{"seller can't confirm a shipment arrived", OrderStatusShipped, OrderStatusDelivered, ActorSeller, ErrTransitionForbidden},
+		{"shipped order can't be cancelled", OrderStatusShipped, OrderStatusCancelled, ActorBuyer, ErrInvalidTransition},
+		{"order ready for pickup can be cancelled", OrderStatusReady, OrderStatusCancelled, ActorSeller, nil},
+		{"delivered is final", OrderStatusDelivered, OrderStatusCancelled, ActorSystem, ErrInvalidTransition},
+		{"cancelled is final", OrderStatusCancelled, OrderStatusPaid, ActorSystem, ErrInvalidTransition},
+		{"no move to the same status", OrderStatusPaid, OrderStatusPaid, ActorSystem, ErrInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.from.CheckTransition(tt.to, tt.actor)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s -> %s by %s: got %v, want %v", tt.from, tt.to, tt.actor, err, tt.want)
+			}
+		})
+	}
+}
@@ -2,26 +2,37 @@ package models
 
 import (
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Payment methods buyers can check out with
+const (
+	PaymentMethodCreditCard = "credit_card"
+	PaymentMethodPayPal     = "paypal"
+	// PaymentMethodCashOnMeetup is cash or e-Transfer paid to the seller
+	// when the item is handed over, outside the app
+	PaymentMethodCashOnMeetup = "cash_on_meetup"
+)
+
 type Payment struct {
-	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	OrderID       uuid.UUID `gorm:"type:uuid;not null"`
-	Amount        float64   `gorm:"type:decimal(10,2);not null"`
-	Currency      string    `gorm:"type:varchar(3);not null"`
-	Status        string    `gorm:"type:varchar(20);not null"`
-	PaymentMethod string    `gorm:"type:varchar(50);not null"`
-	StripeID      string    `gorm:"type:varchar(255);unique"`
-	Error         string    `gorm:"type:text"`
+	ID            uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CheckoutID    uuid.UUID   `gorm:"type:uuid;index"`
+	OrderID       *uuid.UUID  `gorm:"type:uuid"` // Only set on cash payments and payments made before checkouts
+	Amount        money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	Status        string      `gorm:"type:varchar(20);not null"`
+	PaymentMethod string      `gorm:"type:varchar(50);not null"`
+	Provider      string      `gorm:"type:varchar(20);not null;default:'stripe'"`
+	ProviderID    string      `gorm:"type:varchar(255);unique"` // The provider's ID for the payment
+	Error         string      `gorm:"type:text"`
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	DeletedAt     gorm.DeletedAt `gorm:"index"`
 
 	// Relationships
-	Order Order `gorm:"foreignKey:OrderID"`
+	Checkout Checkout `gorm:"foreignKey:CheckoutID"`
 }
 
 func (p *Payment) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PayoutStatus string
+
+const (
+	PayoutStatusPending PayoutStatus = "pending"
+	PayoutStatusPaid    PayoutStatus = "paid"
+	PayoutStatusFailed  PayoutStatus = "failed" // The money went back to the seller's available balance
+)
+
+// Payout is a transfer of a seller's available balance to their account with
+// the payout provider
+type Payout struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID     uuid.UUID    `gorm:"type:uuid;not null;index"`
+	User       User         `gorm:"foreignKey:UserID"`
+	Amount     money.Money  `gorm:"embedded;embeddedPrefix:amount_"`
+	Status     PayoutStatus `gorm:"type:varchar(20);not null"`
+	Provider   string       `gorm:"type:varchar(20);not null"`
+	ProviderID string       `gorm:"type:varchar(255)"` // The provider's ID for the transfer, once made
+	Error      string       `gorm:"type:text"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// BeforeCreate is called before inserting a new payout
+func (p *Payout) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"strings"
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -27,22 +29,28 @@ const (
 )
 
 type Product struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	UserID      uuid.UUID      `json:"user_id" gorm:"type:uuid;not null"`
-	Title       string         `json:"title" gorm:"size:255;not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Size        string         `json:"size" gorm:"size:10;not null"`
-	Brand       string         `json:"brand" gorm:"size:100"`
-	Category    string         `json:"category" gorm:"size:50;not null"`
-	Condition   string         `json:"condition" gorm:"size:50;not null"`
-	ListingType ListingType    `json:"listing_type" gorm:"not null"`
-	Price       float64        `json:"price" gorm:"not null"`
-	IsAvailable bool           `json:"is_available" gorm:"default:true"`
-	Images      pq.StringArray `json:"images" gorm:"type:text[]"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-	User        User           `json:"user" gorm:"foreignkey:UserID"`
+	ID          uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID      uuid.UUID   `json:"user_id" gorm:"type:uuid;not null"`
+	Title       string      `json:"title" gorm:"size:255;not null"`
+	Description string      `json:"description" gorm:"type:text"`
+	Size        string      `json:"size" gorm:"size:10;not null"`
+	Brand       string      `json:"brand" gorm:"size:100"`
+	BrandNorm   string      `json:"-" gorm:"column:brand_normalized;size:100;index"`
+	Category    string      `json:"category" gorm:"size:50;not null"`
+	Condition   string      `json:"condition" gorm:"size:50;not null"`
+	ListingType ListingType `json:"listing_type" gorm:"not null"`
+	Price       money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	IsAvailable bool        `json:"is_available" gorm:"default:true"`
+	AcceptsCash bool        `json:"accepts_cash" gorm:"default:false"` // The seller takes cash or e-Transfer at a meetup
+	// ReservedForID and ReservedUntil hold the listing for a single buyer,
+	// e.g. after the seller accepts their offer.
+	ReservedForID *uuid.UUID     `json:"-" gorm:"type:uuid"`
+	ReservedUntil *time.Time     `json:"-"`
+	Images        pq.StringArray `json:"images" gorm:"type:text[]"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	User          User           `json:"user" gorm:"foreignkey:UserID"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -52,3 +60,21 @@ func (product *Product) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// BeforeSave keeps the normalized brand used for filtering in sync
+func (product *Product) BeforeSave(tx *gorm.DB) error {
+	product.BrandNorm = NormalizeBrand(product.Brand)
+	return nil
+}
+
+// IsReservedForOther reports whether the product is currently held for someone
+// other than userID
+func (product *Product) IsReservedForOther(userID uuid.UUID, now time.Time) bool {
+	return product.ReservedForID != nil && *product.ReservedForID != userID &&
+		product.ReservedUntil != nil && product.ReservedUntil.After(now)
+}
+
+// NormalizeBrand returns the form of a brand name used for case-insensitive matching
+func NormalizeBrand(brand string) string {
+	return strings.ToLower(strings.TrimSpace(brand))
+}
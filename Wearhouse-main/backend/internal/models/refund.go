@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// Refund represents money returned to the buyer against a payment, usually
+// because one of the checkout's orders was cancelled
+type Refund struct {
+	ID          uuid.UUID    `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PaymentID   uuid.UUID    `gorm:"type:uuid;not null;index"`
+	Payment     Payment      `gorm:"foreignKey:PaymentID"`
+	OrderID     *uuid.UUID   `gorm:"type:uuid;index"` // Nil for refunds issued outside the app
+	Amount      money.Money  `gorm:"embedded;embeddedPrefix:amount_"`
+	Status      RefundStatus `gorm:"type:varchar(20);not null"`
+	ProviderID  string       `gorm:"type:varchar(255);unique"` // The provider's ID for the refund
+	Reason      string       `gorm:"type:text"`
+	InitiatedBy OrderActor   `gorm:"type:varchar(20);not null"`
+	Error       string       `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BeforeCreate is called before inserting a new refund
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShipmentStatus string
+
+const (
+	ShipmentLabelCreated ShipmentStatus = "label_created"
+	ShipmentInTransit    ShipmentStatus = "in_transit"
+	ShipmentDelivered    ShipmentStatus = "delivered"
+	ShipmentException    ShipmentStatus = "exception"
+)
+
+// Shipment is the parcel a shipped order travels in
+type Shipment struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrderID        uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex"`
+	Carrier        string         `gorm:"type:varchar(50);not null"`
+	ServiceCode    string         `gorm:"type:varchar(50)"` // Empty when the seller bought the label elsewhere
+	TrackingNumber string         `gorm:"type:varchar(100);not null;index"`
+	LabelURL       string         `gorm:"type:text"`
+	Cost           money.Money    `gorm:"embedded;embeddedPrefix:cost_"`
+	Status         ShipmentStatus `gorm:"type:varchar(20);not null;default:'label_created'"`
+	StatusDetail   string         `gorm:"type:text"` // The carrier's own description of the last event
+	LastCheckedAt  *time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// BeforeCreate is called before inserting a new shipment
+func (s *Shipment) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strconv"
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxRate is a sales tax charged in a province, e.g. GST, HST, PST or QST.
+// Rates are never changed once created: a new rate for the same tax takes
+// over from its EffectiveFrom, and orders keep a copy of the rate they were
+// taxed at.
+type TaxRate struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Province      string    `gorm:"type:varchar(2);not null;index:idx_tax_rates_province_name"`
+	Name          string    `gorm:"type:varchar(10);not null;index:idx_tax_rates_province_name"`
+	RatePPM       int64     `gorm:"not null"`              // Parts per million, e.g. 50000 for 5%
+	AppliesToFees bool      `gorm:"not null;default:true"` // Whether our fees are taxed too, not just goods
+	EffectiveFrom time.Time `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
+// Tax is the tax on an amount, rounded to the nearest cent
+func (r *TaxRate) Tax(amount money.Money) money.Money {
+	return money.Cents((amount.Cents*r.RatePPM + 500000) / 1000000)
+}
+
+// RatePercent formats the rate, e.g. "9.975"
+func (r *TaxRate) RatePercent() string {
+	return formatRatePPM(r.RatePPM)
+}
+
+// TaxBase is what a tax line is charged on
+type TaxBase string
+
+const (
+	TaxBaseItem TaxBase = "item"
+	TaxBaseFee  TaxBase = "fee" // The platform fee on the item
+)
+
+// TaxLine is a tax charged on an order item or its fee, with the rate as it
+// was when the order was placed
+type TaxLine struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrderID     uuid.UUID   `gorm:"type:uuid;not null;index"`
+	OrderItemID uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Base        TaxBase     `gorm:"type:varchar(10);not null"`
+	TaxRateID   uuid.UUID   `gorm:"type:uuid;not null"`
+	Name        string      `gorm:"type:varchar(10);not null"`
+	Province    string      `gorm:"type:varchar(2);not null"`
+	RatePPM     int64       `gorm:"not null"`
+	Taxable     money.Money `gorm:"embedded;embeddedPrefix:taxable_"`
+	Amount      money.Money `gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt   time.Time
+}
+
+// RatePercent formats the rate of the line, e.g. "9.975"
+func (l *TaxLine) RatePercent() string {
+	return formatRatePPM(l.RatePPM)
+}
+
+// formatRatePPM turns a rate in parts per million into a percentage
+func formatRatePPM(ppm int64) string {
+	return strconv.FormatFloat(float64(ppm)/10000, 'f', -1, 64)
+}
+
+// BeforeCreate is called before inserting a new tax rate
+func (r *TaxRate) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is called before inserting a new tax line
+func (l *TaxLine) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
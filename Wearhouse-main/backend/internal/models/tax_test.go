@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+	"wearhouse/internal/money"
+)
+
+func TestTaxRateTax(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratePPM int64
+		amount  int64
+		want    int64
+	}{
+		{"gst", 50000, 2500, 125},
+		{"hst", 130000, 1999, 260},
+		{"qst rounds up", 99750, 1000, 100},
+		{"qst rounds down", 99750, 1003, 100},
+		{"exactly half a cent", 50000, 10, 1},
+		{"under half a cent", 50000, 9, 0},
+		{"fee taxed on its own", 130000, 155, 20},
+		{"zero rate", 0, 2500, 0},
+		{"nothing to tax", 130000, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := TaxRate{RatePPM: tt.ratePPM}
+			got := rate.Tax(money.Cents(tt.amount))
+			if got.Cents != tt.want {
+				t.Errorf("Tax(%d) at %d ppm = %d cents, want %d", tt.amount, tt.ratePPM, got.Cents, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaxRateRatePercent(t *testing.T) {
+	tests := []struct {
+		ratePPM int64
+		want    string
+	}{
+		{50000, "5"},
+		{130000, "13"},
+		{99750, "9.975"},
+		{0, "0"},
+	}
+
+	for _, tt := range tests {
+		rate := TaxRate{RatePPM: tt.ratePPM}
+		if got := rate.RatePercent(); got != tt.want {
+			t.Errorf("RatePercent() at %d ppm = %q, want %q", tt.ratePPM, got, tt.want)
+		}
+	}
+}
@@ -8,14 +8,18 @@ import (
 )
 
 type User struct {
-	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	Email             string    `gorm:"type:varchar(255);unique;not null"`
-	Password          string    `gorm:"type:varchar(255);not null"`
-	FirstName         string    `gorm:"type:varchar(100);not null"`
-	LastName          string    `gorm:"type:varchar(100);not null"`
-	University        string    `gorm:"type:varchar(255);not null"`
-	IsVerified        bool      `gorm:"default:false"`
-	VerificationToken string    `gorm:"type:varchar(255);unique"`
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Email             string     `gorm:"type:varchar(255);unique;not null"`
+	Password          string     `gorm:"type:varchar(255);not null"`
+	FirstName         string     `gorm:"type:varchar(100);not null"`
+	LastName          string     `gorm:"type:varchar(100);not null"`
+	University        string     `gorm:"type:varchar(255);not null"`
+	IsVerified        bool       `gorm:"default:false"`
+	IsSuspended       bool       `gorm:"default:false"` // Suspended users' listings can't be bought
+	IsAdmin           bool       `gorm:"default:false"`
+	PayoutAccountID   string     `gorm:"type:varchar(255)"` // The seller's account with the payout provider
+	SellerTier        SellerTier `gorm:"type:varchar(20);not null;default:'standard'"`
+	VerificationToken string     `gorm:"type:varchar(255);unique"`
 	TokenExpiresAt    time.Time
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEventStatus string
+
+const (
+	WebhookEventReceived   WebhookEventStatus = "received"
+	WebhookEventProcessing WebhookEventStatus = "processing"
+	WebhookEventProcessed  WebhookEventStatus = "processed"
+	WebhookEventFailed     WebhookEventStatus = "failed"
+)
+
+// WebhookEvent is a webhook delivered by a payment provider. Events are kept
+// by their provider ID so a redelivery is only processed once, and with their
+// raw payload so a failed one can be replayed.
+type WebhookEvent struct {
+	ID          uuid.UUID          `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Provider    string             `gorm:"type:varchar(20);not null;uniqueIndex:idx_webhook_events_provider_event"`
+	EventID     string             `gorm:"type:varchar(255);not null;uniqueIndex:idx_webhook_events_provider_event"`
+	Type        string             `gorm:"type:varchar(100);not null;index"`
+	Payload     []byte             `gorm:"type:bytea;not null"`
+	Status      WebhookEventStatus `gorm:"type:varchar(20);not null;index"`
+	Attempts    int                `gorm:"default:0"`
+	Error       string             `gorm:"type:text"`
+	ProcessedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BeforeCreate is called before inserting a new webhook event
+func (e *WebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
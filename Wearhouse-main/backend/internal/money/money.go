@@ -0,0 +1,93 @@
+package money
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency is the currency every price on the marketplace is in
+const Currency = "cad"
+
+// Money is an amount in the minor units of a currency, e.g. cents. Totals
+// are always added up in minor units so no cent is lost to rounding.
+//
+// In JSON it is a plain decimal number of major units (12.5 for $12.50), as
+// prices were before they were stored in cents. Models embed it with a
+// prefix, so a Price is stored as price_cents and price_currency.
+type Money struct {
+	Cents    int64  `gorm:"not null;default:0"`
+	Currency string `gorm:"type:varchar(3);not null;default:'cad'"`
+}
+
+// Cents returns an amount of cents in the marketplace currency
+func Cents(cents int64) Money {
+	return Money{Cents: cents, Currency: Currency}
+}
+
+// FromFloat converts an amount in major units, as clients send them,
+// rounding to the nearest cent
+func FromFloat(amount float64) Money {
+	return Cents(int64(math.Round(amount * 100)))
+}
+
+// Float returns the amount in major units, for display only
+func (m Money) Float() float64 {
+	return float64(m.Cents) / 100
+}
+
+// Add returns the sum of m and other. Amounts in no currency yet take the
+// currency of the other.
+func (m Money) Add(other Money) Money {
+	currency := m.Currency
+	if currency == "" {
+		currency = other.Currency
+	}
+	return Money{Cents: m.Cents + other.Cents, Currency: currency}
+}
+
+// Sub returns m less other
+func (m Money) Sub(other Money) Money {
+	return m.Add(Money{Cents: -other.Cents, Currency: other.Currency})
+}
+
+// Mul returns m times n, e.g. a unit price times a quantity
+func (m Money) Mul(n int) Money {
+	return Money{Cents: m.Cents * int64(n), Currency: m.Currency}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Cents == 0
+}
+
+// String formats the amount in major units, e.g. "12.50"
+func (m Money) String() string {
+	cents := m.Cents
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MarshalJSON writes the amount as a decimal number of major units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(m.Float(), 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON reads a number of major units, or a quoted one
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" {
+		return nil
+	}
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", text)
+	}
+	*m = FromFloat(amount)
+	return nil
+}
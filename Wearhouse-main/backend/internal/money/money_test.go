@@ -0,0 +1,152 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		want   int64
+	}{
+		{"whole dollars", 12, 1200},
+		{"dollars and cents", 12.5, 1250},
+		{"float error rounds up", 0.29, 29},
+		{"float error below the cent", 1.15, 115},
+		{"half a cent rounds away from zero", 0.125, 13},
+		{"under half a cent", 0.004, 0},
+		{"negative", -3.75, -375},
+		{"zero", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromFloat(tt.amount)
+			if got.Cents != tt.want {
+				t.Errorf("FromFloat(%v) = %d cents, want %d", tt.amount, got.Cents, tt.want)
+			}
+			if got.Currency != Currency {
+				t.Errorf("FromFloat(%v) currency = %q, want %q", tt.amount, got.Currency, Currency)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         Money
+		wantCents    int64
+		wantCurrency string
+	}{
+		{"two amounts", Cents(1250), Cents(375), 1625, Currency},
+		{"subtracting", Cents(1250), Cents(-1300), -50, Currency},
+		{"zero value takes the other currency", Money{}, Cents(500), 500, Currency},
+		{"adding a zero value keeps the currency", Cents(500), Money{}, 500, Currency},
+		{"two zero values", Money{}, Money{}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.a.Add(tt.b)
+			if got.Cents != tt.wantCents || got.Currency != tt.wantCurrency {
+				t.Errorf("%v.Add(%v) = %+v, want %d %q", tt.a, tt.b, got, tt.wantCents, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestSub(t *testing.T) {
+	got := Cents(1250).Sub(Cents(300))
+	if got.Cents != 950 || got.Currency != Currency {
+		t.Errorf("Cents(1250).Sub(Cents(300)) = %+v, want 950 %q", got, Currency)
+	}
+	if got := (Money{}).Sub(Cents(300)); got.Cents != -300 || got.Currency != Currency {
+		t.Errorf("Money{}.Sub(Cents(300)) = %+v, want -300 %q", got, Currency)
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		n      int
+		want   int64
+	}{
+		{"one", Cents(1999), 1, 1999},
+		{"several", Cents(1999), 3, 5997},
+		{"none", Cents(1999), 0, 0},
+		{"negative", Cents(-250), 2, -500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Mul(tt.n)
+			if got.Cents != tt.want {
+				t.Errorf("%v.Mul(%d) = %d cents, want %d", tt.amount, tt.n, got.Cents, tt.want)
+			}
+			if got.Currency != tt.amount.Currency {
+				t.Errorf("%v.Mul(%d) currency = %q, want %q", tt.amount, tt.n, got.Currency, tt.amount.Currency)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		cents int64
+		want  string
+	}{
+		{1250, "12.50"},
+		{5, "0.05"},
+		{0, "0.00"},
+		{-5, "-0.05"},
+		{-1250, "-12.50"},
+	}
+
+	for _, tt := range tests {
+		if got := Cents(tt.cents).String(); got != tt.want {
+			t.Errorf("Cents(%d).String() = %q, want %q", tt.cents, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		json  string
+		cents int64
+		out   string
+	}{
+		{"number", `12.5`, 1250, `12.5`},
+		{"quoted number", `"12.50"`, 1250, `12.5`},
+		{"whole", `3`, 300, `3`},
+		{"rounds to the cent", `0.295`, 30, `0.3`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			if err := json.Unmarshal([]byte(tt.json), &m); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", tt.json, err)
+			}
+			if m.Cents != tt.cents {
+				t.Errorf("Unmarshal(%s) = %d cents, want %d", tt.json, m.Cents, tt.cents)
+			}
+			out, err := json.Marshal(m)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(out) != tt.out {
+				t.Errorf("Marshal = %s, want %s", out, tt.out)
+			}
+		})
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`"abc"`), &m); err == nil {
+		t.Error("Unmarshal accepted a non-number")
+	}
+}
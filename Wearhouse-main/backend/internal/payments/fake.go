@@ -0,0 +1,285 @@
+package payments
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Test payment methods the fake understands. They are named after Stripe's
+// test cards so the same client code works against both.
+const (
+	FakeCardSucceeds = "pm_card_visa"
+	FakeCardDeclined = "pm_card_chargeDeclined"
+	FakeCard3DS      = "pm_card_threeDSecure2Required"
+)
+
+const (
+	// fakeWebhookDelay gives the request that caused an event time to finish
+	// before the event arrives, as it usually would with a real provider
+	fakeWebhookDelay = time.Second
+	// fakeWebhookTolerance is how old a signed webhook can be
+	fakeWebhookTolerance = 5 * time.Minute
+)
+
+// Fake is a payment provider for local development and tests. It keeps
+// payments in memory, never calls out except to deliver signed webhooks to
+// our own endpoint, and needs no account. A Fake only knows the payments it
+// made, and forgets them on restart; New shares one across the process.
+//
+// Confirming with FakeCardSucceeds pays, FakeCardDeclined is declined and
+// FakeCard3DS asks for authentication. Confirming a payment waiting for
+// authentication again completes the challenge, and fails it when done with
+// FakeCardDeclined.
+type Fake struct {
+	config  configs.FakePaymentsConfig
+	client  *http.Client
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	Intent
+	refunds       []Refund
+	refundedCents int64
+}
+
+func NewFake(config configs.FakePaymentsConfig) *Fake {
+	return &Fake{
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		intents: make(map[string]*fakeIntent),
+	}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) CreateIntent(req IntentRequest) (*Intent, error) {
+	if req.Amount.Cents <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	id := "fake_pi_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	intent := &fakeIntent{Intent: Intent{
+		ID:           id,
+		ClientSecret: id + "_secret_" + uuid.NewString()[:8],
+		Amount:       req.Amount,
+		Status:       IntentRequiresPaymentMethod,
+	}}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.intents[id] = intent
+
+	result := intent.Intent
+	return &result, nil
+}
+
+func (f *Fake) GetIntent(id string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment intent %q", id)
+	}
+	result := intent.Intent
+	return &result, nil
+}
+
+func (f *Fake) CancelIntent(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[id]
+	if !ok {
+		return fmt.Errorf("unknown payment intent %q", id)
+	}
+	if !intent.Status.Payable() {
+		return fmt.Errorf("payment intent %s is %s", id, intent.Status)
+	}
+
+	intent.Status = IntentCanceled
+	f.emit(&Event{Type: EventPaymentCanceled, RawType: "payment_intent.canceled", Intent: intent.snapshot()})
+	return nil
+}
+
+func (f *Fake) Confirm(id string, req ConfirmRequest) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment intent %q", id)
+	}
+	if !intent.Status.Payable() {
+		return nil, fmt.Errorf("payment intent %s is %s", id, intent.Status)
+	}
+
+	// Answering the authentication challenge
+	if intent.Status == IntentRequiresAction {
+		if req.PaymentMethod == FakeCardDeclined {
+			f.decline(intent, "Authentication failed.")
+		} else {
+			f.succeed(intent)
+		}
+		return intent.snapshot(), nil
+	}
+
+	switch req.PaymentMethod {
+	case FakeCardSucceeds:
+		f.succeed(intent)
+	case FakeCardDeclined:
+		f.decline(intent, "Your card was declined.")
+	case FakeCard3DS:
+		intent.Status = IntentRequiresAction
+		intent.NextActionURL = req.ReturnURL
+		intent.FailureMessage = ""
+	default:
+		return nil, fmt.Errorf("unknown fake payment method %q", req.PaymentMethod)
+	}
+
+	return intent.snapshot(), nil
+}
+
+func (f *Fake) Refund(req RefundRequest) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[req.IntentID]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment intent %q", req.IntentID)
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("payment intent %s is %s", req.IntentID, intent.Status)
+	}
+	if req.Amount.Cents <= 0 || intent.refundedCents+req.Amount.Cents > intent.Amount.Cents {
+		return nil, fmt.Errorf("cannot refund %s of %s left", req.Amount, money.Cents(intent.Amount.Cents-intent.refundedCents))
+	}
+
+	refund := Refund{
+		ID:     "fake_re_" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Status: models.RefundStatusSucceeded,
+	}
+	intent.refunds = append(intent.refunds, refund)
+	intent.refundedCents += req.Amount.Cents
+
+	f.emit(&Event{Type: EventRefundUpdated, RawType: "charge.refunded", Refunds: &RefundUpdate{
+		IntentID:      intent.ID,
+		Refunds:       append([]Refund(nil), intent.refunds...),
+		FullyRefunded: intent.refundedCents >= intent.Amount.Cents,
+	}})
+
+	return &refund, nil
+}
+
+// ParseWebhook checks the Fake-Signature header, which is signed like
+// Stripe's: t=<unix time>,v1=<hex HMAC-SHA256 of "<time>.<payload>">
+func (f *Fake) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get("Fake-Signature"), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(unix, 0)) > fakeWebhookTolerance {
+		return nil, ErrInvalidSignature
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, f.sign(timestamp, payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	return f.DecodeEvent(payload)
+}
+
+func (f *Fake) DecodeEvent(payload []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid fake event: %w", err)
+	}
+	return &event, nil
+}
+
+func (f *Fake) succeed(intent *fakeIntent) {
+	intent.Status = IntentSucceeded
+	intent.NextActionURL = ""
+	intent.FailureMessage = ""
+	f.emit(&Event{Type: EventPaymentSucceeded, RawType: "payment_intent.succeeded", Intent: intent.snapshot()})
+}
+
+// decline leaves the intent open for another payment method, like Stripe
+func (f *Fake) decline(intent *fakeIntent, message string) {
+	intent.Status = IntentRequiresPaymentMethod
+	intent.NextActionURL = ""
+	intent.FailureMessage = message
+	f.emit(&Event{Type: EventPaymentFailed, RawType: "payment_intent.payment_failed", Intent: intent.snapshot()})
+}
+
+func (f *Fake) sign(timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(f.config.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// emit delivers a signed webhook to our own endpoint in the background
+func (f *Fake) emit(event *Event) {
+	event.ID = "fake_evt_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding fake webhook %s: %v", event.ID, err)
+		return
+	}
+
+	go func() {
+		time.Sleep(fakeWebhookDelay)
+
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req, err := http.NewRequest(http.MethodPost, f.config.WebhookURL, bytes.NewReader(payload))
+		if err != nil {
+			log.Printf("Error delivering fake webhook %s: %v", event.ID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Fake-Signature", "t="+timestamp+",v1="+hex.EncodeToString(f.sign(timestamp, payload)))
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			log.Printf("Error delivering fake webhook %s: %v", event.ID, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Fake webhook %s (%s) was answered with %d", event.ID, event.RawType, resp.StatusCode)
+		}
+	}()
+}
+
+func (i *fakeIntent) snapshot() *Intent {
+	result := i.Intent
+	return &result
+}
@@ -0,0 +1,191 @@
+package payments
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+)
+
+// delivery is a webhook the fake sent to our endpoint
+type delivery struct {
+	payload []byte
+	header  http.Header
+}
+
+// newTestFake returns a fake whose webhooks are delivered to the returned
+// channel
+func newTestFake(t *testing.T) (*Fake, <-chan delivery) {
+	t.Helper()
+	webhooks := make(chan delivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		webhooks <- delivery{payload: payload, header: r.Header.Clone()}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewFake(configs.FakePaymentsConfig{WebhookURL: server.URL, WebhookSecret: "whsec_test"}), webhooks
+}
+
+func nextWebhook(t *testing.T, webhooks <-chan delivery) delivery {
+	t.Helper()
+	select {
+	case hook := <-webhooks:
+		return hook
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook was sent")
+		return delivery{}
+	}
+}
+
+func TestFakeConfirmOutcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		methods    []string // Confirmed with in turn, the last one decides
+		wantStatus IntentStatus
+		wantEvent  EventType // Empty when no webhook is sent
+	}{
+		{"card succeeds", []string{FakeCardSucceeds}, IntentSucceeded, EventPaymentSucceeded},
+		{"card declined", []string{FakeCardDeclined}, IntentRequiresPaymentMethod, EventPaymentFailed},
+		{"3DS required", []string{FakeCard3DS}, IntentRequiresAction, ""},
+		{"3DS completed", []string{FakeCard3DS, FakeCardSucceeds}, IntentSucceeded, EventPaymentSucceeded},
+		{"3DS failed", []string{FakeCard3DS, FakeCardDeclined}, IntentRequiresPaymentMethod, EventPaymentFailed},
+		{"another card after a decline", []string{FakeCardDeclined, FakeCardSucceeds}, IntentSucceeded, EventPaymentSucceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel() // Each webhook takes fakeWebhookDelay to arrive
+			fake, webhooks := newTestFake(t)
+			intent, err := fake.CreateIntent(IntentRequest{Amount: money.Cents(2500)})
+			if err != nil {
+				t.Fatalf("CreateIntent: %v", err)
+			}
+
+			var event *Event
+			for _, method := range tt.methods {
+				intent, err = fake.Confirm(intent.ID, ConfirmRequest{PaymentMethod: method, ReturnURL: "https://example.com/return"})
+				if err != nil {
+					t.Fatalf("Confirm(%s): %v", method, err)
+				}
+				if intent.Status != IntentRequiresAction {
+					hook := nextWebhook(t, webhooks)
+					if event, err = fake.ParseWebhook(hook.payload, hook.header); err != nil {
+						t.Fatalf("ParseWebhook: %v", err)
+					}
+				}
+			}
+
+			if intent.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", intent.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == IntentRequiresAction && intent.NextActionURL == "" {
+				t.Error("3DS payment has no next action URL")
+			}
+			if tt.wantEvent == "" {
+				return
+			}
+			if event == nil || event.Type != tt.wantEvent {
+				t.Fatalf("event = %+v, want %s", event, tt.wantEvent)
+			}
+			if event.Intent == nil || event.Intent.ID != intent.ID || event.Intent.Amount.Cents != 2500 {
+				t.Errorf("event is for %+v, want intent %s of 2500 cents", event.Intent, intent.ID)
+			}
+		})
+	}
+}
+
+func TestFakeUnknownPaymentMethod(t *testing.T) {
+	fake := NewFake(configs.FakePaymentsConfig{})
+	intent, err := fake.CreateIntent(IntentRequest{Amount: money.Cents(2500)})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err := fake.Confirm(intent.ID, ConfirmRequest{PaymentMethod: "pm_card_mystery"}); err == nil {
+		t.Error("Confirm accepted an unknown payment method")
+	}
+}
+
+func TestFakeWebhookSignature(t *testing.T) {
+	fake, webhooks := newTestFake(t)
+	intent, err := fake.CreateIntent(IntentRequest{Amount: money.Cents(2500)})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if _, err := fake.Confirm(intent.ID, ConfirmRequest{PaymentMethod: FakeCardSucceeds}); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if _, err := fake.Refund(RefundRequest{IntentID: intent.ID, Amount: money.Cents(2500)}); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+
+	// Webhooks are sent in the background, in no set order
+	var paid delivery
+	var refundEvent *Event
+	for range 2 {
+		hook := nextWebhook(t, webhooks)
+		event, err := fake.ParseWebhook(hook.payload, hook.header)
+		if err != nil {
+			t.Fatalf("signed webhook was refused: %v", err)
+		}
+		switch event.Type {
+		case EventPaymentSucceeded:
+			paid = hook
+		case EventRefundUpdated:
+			refundEvent = event
+		}
+	}
+	if paid.payload == nil {
+		t.Fatal("no payment webhook was sent")
+	}
+	if refundEvent == nil || refundEvent.Refunds == nil || !refundEvent.Refunds.FullyRefunded ||
+		len(refundEvent.Refunds.Refunds) != 1 || refundEvent.Refunds.Refunds[0].Status != models.RefundStatusSucceeded {
+		t.Errorf("refund event = %+v, want one succeeded refund, fully refunded", refundEvent)
+	}
+
+	tampered := append([]byte(nil), paid.payload...)
+	tampered[len(tampered)-2] ^= 1
+	otherSecret := NewFake(configs.FakePaymentsConfig{WebhookSecret: "whsec_other"})
+	unsigned := http.Header{}
+	stale := http.Header{}
+	_, signature, _ := strings.Cut(paid.header.Get("Fake-Signature"), ",v1=")
+	stale.Set("Fake-Signature", "t=1000000000,v1="+signature)
+
+	tests := []struct {
+		name    string
+		fake    *Fake
+		payload []byte
+		header  http.Header
+	}{
+		{"payload changed", fake, tampered, paid.header},
+		{"signed with another secret", otherSecret, paid.payload, paid.header},
+		{"no signature", fake, paid.payload, unsigned},
+		{"too old", fake, paid.payload, stale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.fake.ParseWebhook(tt.payload, tt.header); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("ParseWebhook = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestNewSharesTheFake(t *testing.T) {
+	config := &configs.Config{PaymentProvider: "fake", FakePayments: configs.FakePaymentsConfig{WebhookSecret: "whsec_shared"}}
+
+	// The server and the jobs each ask for the providers
+	intent, err := New(config)[models.PaymentMethodCreditCard].CreateIntent(IntentRequest{Amount: money.Cents(2500)})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if err := New(config)[models.PaymentMethodCreditCard].CancelIntent(intent.ID); err != nil {
+		t.Errorf("a later New doesn't know the intent: %v", err)
+	}
+}
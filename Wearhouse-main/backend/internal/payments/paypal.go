@@ -0,0 +1,486 @@
+package payments
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+)
+
+// PayPal takes payments through the PayPal Orders v2 API. Creating an intent
+// creates a PayPal order the buyer approves on PayPal, and confirming it
+// captures the approved order.
+type PayPal struct {
+	config configs.PayPalConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewPayPal(config configs.PayPalConfig) *PayPal {
+	return &PayPal{
+		config: config,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *PayPal) Name() string {
+	return "paypal"
+}
+
+type ppAmount struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+type ppLink struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+type ppCapture struct {
+	ID                string    `json:"id"`
+	Status            string    `json:"status"`
+	Amount            *ppAmount `json:"amount"`
+	SupplementaryData struct {
+		RelatedIDs struct {
+			OrderID string `json:"order_id"`
+		} `json:"related_ids"`
+	} `json:"supplementary_data"`
+	StatusDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"status_details"`
+	Links []ppLink `json:"links"`
+}
+
+type ppOrder struct {
+	ID            string `json:"id"`
+	Status        string `json:"status"`
+	PurchaseUnits []struct {
+		Amount   ppAmount `json:"amount"`
+		Payments struct {
+			Captures []ppCapture `json:"captures"`
+		} `json:"payments"`
+	} `json:"purchase_units"`
+	Links []ppLink `json:"links"`
+}
+
+type ppError struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Details []struct {
+		Issue       string `json:"issue"`
+		Description string `json:"description"`
+	} `json:"details"`
+}
+
+func (e *ppError) Error() string {
+	if len(e.Details) > 0 {
+		return fmt.Sprintf("paypal: %s: %s", e.Details[0].Issue, e.Details[0].Description)
+	}
+	return fmt.Sprintf("paypal: %s: %s", e.Name, e.Message)
+}
+
+// CreateIntent creates a PayPal order for the buyer to approve
+func (p *PayPal) CreateIntent(req IntentRequest) (*Intent, error) {
+	unit := map[string]interface{}{
+		"amount":      ppAmount{CurrencyCode: strings.ToUpper(req.Amount.Currency), Value: ppValue(req.Amount.Cents)},
+		"custom_id":   req.Metadata["checkout_id"],
+		"description": req.Description,
+	}
+	body := map[string]interface{}{
+		"intent":         "CAPTURE",
+		"purchase_units": []interface{}{unit},
+		"application_context": map[string]string{
+			"return_url":  req.ReturnURL,
+			"cancel_url":  req.CancelURL,
+			"user_action": "PAY_NOW",
+		},
+	}
+
+	var order ppOrder
+	if err := p.do(http.MethodPost, "/v2/checkout/orders", body, &order); err != nil {
+		return nil, err
+	}
+	return ppIntent(&order), nil
+}
+
+func (p *PayPal) GetIntent(id string) (*Intent, error) {
+	var order ppOrder
+	if err := p.do(http.MethodGet, "/v2/checkout/orders/"+url.PathEscape(id), nil, &order); err != nil {
+		return nil, err
+	}
+	return ppIntent(&order), nil
+}
+
+// CancelIntent doesn't call PayPal. Only authorizations can be voided, not
+// orders to capture, which expire when nobody captures them. The buyer can
+// still approve a cancelled order on PayPal, but no money moves until it is
+// captured in Confirm, and payments are only confirmed while pending. A
+// capture that raced the cancel is refunded when its webhook arrives.
+func (p *PayPal) CancelIntent(id string) error {
+	return nil
+}
+
+// Confirm captures an order the buyer approved. The payment method is
+// whatever the buyer picked on PayPal.
+func (p *PayPal) Confirm(id string, req ConfirmRequest) (*Intent, error) {
+	var order ppOrder
+	err := p.do(http.MethodPost, "/v2/checkout/orders/"+url.PathEscape(id)+"/capture", struct{}{}, &order)
+	if err != nil {
+		// A declined funding source sends the buyer back to PayPal to pick another
+		var ppErr *ppError
+		if errors.As(err, &ppErr) && len(ppErr.Details) > 0 && ppErr.Details[0].Issue == "INSTRUMENT_DECLINED" {
+			intent, getErr := p.GetIntent(id)
+			if getErr != nil {
+				return nil, err
+			}
+			intent.Status = IntentRequiresAction
+			intent.FailureMessage = ppErr.Details[0].Description
+			return intent, nil
+		}
+		return nil, err
+	}
+	return ppIntent(&order), nil
+}
+
+// Refund refunds the capture of a completed order
+func (p *PayPal) Refund(req RefundRequest) (*Refund, error) {
+	var order ppOrder
+	if err := p.do(http.MethodGet, "/v2/checkout/orders/"+url.PathEscape(req.IntentID), nil, &order); err != nil {
+		return nil, err
+	}
+	capture := ppFirstCapture(&order)
+	if capture == nil {
+		return nil, fmt.Errorf("paypal: order %s has not been captured", req.IntentID)
+	}
+	currency := strings.ToUpper(money.Currency)
+	if capture.Amount != nil {
+		currency = capture.Amount.CurrencyCode
+	}
+
+	body := map[string]interface{}{
+		"amount":        ppAmount{CurrencyCode: currency, Value: ppValue(req.Amount.Cents)},
+		"invoice_id":    req.Metadata["order_id"],
+		"note_to_payer": "Refund from Wearhouse",
+	}
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := p.do(http.MethodPost, "/v2/payments/captures/"+url.PathEscape(capture.ID)+"/refund", body, &refund); err != nil {
+		return nil, err
+	}
+	return &Refund{ID: refund.ID, Status: ppRefundStatus(refund.Status)}, nil
+}
+
+type ppWebhookEvent struct {
+	ID        string          `json:"id"`
+	EventType string          `json:"event_type"`
+	Resource  json.RawMessage `json:"resource"`
+}
+
+// ParseWebhook asks PayPal whether it sent the webhook, using the
+// transmission headers and the webhook ID it was registered with
+func (p *PayPal) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	body := map[string]interface{}{
+		"auth_algo":         header.Get("Paypal-Auth-Algo"),
+		"cert_url":          header.Get("Paypal-Cert-Url"),
+		"transmission_id":   header.Get("Paypal-Transmission-Id"),
+		"transmission_sig":  header.Get("Paypal-Transmission-Sig"),
+		"transmission_time": header.Get("Paypal-Transmission-Time"),
+		"webhook_id":        p.config.WebhookID,
+		"webhook_event":     json.RawMessage(payload),
+	}
+	var result struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := p.do(http.MethodPost, "/v1/notifications/verify-webhook-signature", body, &result); err != nil {
+		return nil, err
+	}
+	if result.VerificationStatus != "SUCCESS" {
+		return nil, ErrInvalidSignature
+	}
+
+	return p.DecodeEvent(payload)
+}
+
+// DecodeEvent translates a PayPal webhook. Refund and dispute events only
+// name the capture, so the capture is looked up to find its order.
+func (p *PayPal) DecodeEvent(payload []byte) (*Event, error) {
+	var raw ppWebhookEvent
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid paypal event: %w", err)
+	}
+
+	event := &Event{ID: raw.ID, RawType: raw.EventType}
+	switch {
+	case raw.EventType == "PAYMENT.CAPTURE.COMPLETED", raw.EventType == "PAYMENT.CAPTURE.DENIED":
+		var capture ppCapture
+		if err := json.Unmarshal(raw.Resource, &capture); err != nil {
+			return nil, fmt.Errorf("invalid capture data: %w", err)
+		}
+		intent := &Intent{ID: capture.SupplementaryData.RelatedIDs.OrderID, Status: IntentSucceeded}
+		if capture.Amount != nil {
+			intent.Amount = ppMoney(*capture.Amount)
+		}
+		event.Type = EventPaymentSucceeded
+		if raw.EventType == "PAYMENT.CAPTURE.DENIED" {
+			intent.Status = IntentRequiresAction
+			intent.FailureMessage = "Payment was denied"
+			if capture.StatusDetails != nil {
+				intent.FailureMessage += ": " + capture.StatusDetails.Reason
+			}
+			event.Type = EventPaymentFailed
+		}
+		event.Intent = intent
+
+	case raw.EventType == "CHECKOUT.PAYMENT-APPROVAL.REVERSED":
+		var resource struct {
+			OrderID string `json:"order_id"`
+		}
+		if err := json.Unmarshal(raw.Resource, &resource); err != nil {
+			return nil, fmt.Errorf("invalid approval data: %w", err)
+		}
+		event.Type = EventPaymentCanceled
+		event.Intent = &Intent{ID: resource.OrderID, Status: IntentCanceled}
+
+	case raw.EventType == "PAYMENT.CAPTURE.REFUNDED", raw.EventType == "PAYMENT.CAPTURE.REVERSED":
+		var refund struct {
+			ID     string   `json:"id"`
+			Status string   `json:"status"`
+			Links  []ppLink `json:"links"`
+		}
+		if err := json.Unmarshal(raw.Resource, &refund); err != nil {
+			return nil, fmt.Errorf("invalid refund data: %w", err)
+		}
+		capture, err := p.getCapture(ppCaptureIDFromLinks(refund.Links))
+		if err != nil {
+			return nil, err
+		}
+		event.Type = EventRefundUpdated
+		event.Refunds = &RefundUpdate{
+			IntentID:      capture.SupplementaryData.RelatedIDs.OrderID,
+			Refunds:       []Refund{{ID: refund.ID, Status: ppRefundStatus(refund.Status)}},
+			FullyRefunded: capture.Status == "REFUNDED",
+		}
+
+	case strings.HasPrefix(raw.EventType, "CUSTOMER.DISPUTE."):
+		var dispute struct {
+			DisputeID            string   `json:"dispute_id"`
+			Reason               string   `json:"reason"`
+			Status               string   `json:"status"`
+			DisputeAmount        ppAmount `json:"dispute_amount"`
+			DisputedTransactions []struct {
+				SellerTransactionID string `json:"seller_transaction_id"`
+			} `json:"disputed_transactions"`
+		}
+		if err := json.Unmarshal(raw.Resource, &dispute); err != nil {
+			return nil, fmt.Errorf("invalid dispute data: %w", err)
+		}
+		if len(dispute.DisputedTransactions) == 0 {
+			return nil, fmt.Errorf("dispute %s has no transaction", dispute.DisputeID)
+		}
+		capture, err := p.getCapture(dispute.DisputedTransactions[0].SellerTransactionID)
+		if err != nil {
+			return nil, err
+		}
+		event.Type = EventDisputeUpdated
+		event.Dispute = &Dispute{
+			ID:       dispute.DisputeID,
+			IntentID: capture.SupplementaryData.RelatedIDs.OrderID,
+			Amount:   ppMoney(dispute.DisputeAmount),
+			Reason:   strings.ToLower(dispute.Reason),
+			Status:   strings.ToLower(dispute.Status),
+			Closed:   dispute.Status == "RESOLVED",
+		}
+	}
+
+	return event, nil
+}
+
+func (p *PayPal) getCapture(id string) (*ppCapture, error) {
+	if id == "" {
+		return nil, errors.New("paypal: event does not name a capture")
+	}
+	var capture ppCapture
+	if err := p.do(http.MethodGet, "/v2/payments/captures/"+url.PathEscape(id), nil, &capture); err != nil {
+		return nil, err
+	}
+	return &capture, nil
+}
+
+// accessToken returns an OAuth token, fetching a new one when it's about to
+// expire
+func (p *PayPal) accessToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.config.BaseURL+"/v1/oauth2/token",
+		strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(p.config.ClientID, p.config.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("paypal: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("paypal: authentication failed with status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("paypal: %w", err)
+	}
+
+	p.token = token.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
+
+// do sends a request to the REST API and decodes the JSON reply into out
+func (p *PayPal) do(method, path string, in, out interface{}) error {
+	token, err := p.accessToken()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, p.config.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("paypal: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("paypal: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var ppErr ppError
+		if json.Unmarshal(data, &ppErr) == nil && (ppErr.Name != "" || len(ppErr.Details) > 0) {
+			return &ppErr
+		}
+		return fmt.Errorf("paypal: unexpected status %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+func ppIntent(order *ppOrder) *Intent {
+	intent := &Intent{ID: order.ID}
+	if len(order.PurchaseUnits) > 0 {
+		intent.Amount = ppMoney(order.PurchaseUnits[0].Amount)
+	}
+
+	switch order.Status {
+	case "APPROVED":
+		intent.Status = IntentRequiresConfirmation
+	case "COMPLETED":
+		intent.Status = IntentSucceeded
+		if capture := ppFirstCapture(order); capture != nil && capture.Status == "PENDING" {
+			intent.Status = IntentProcessing
+		}
+	case "VOIDED":
+		intent.Status = IntentCanceled
+	default: // CREATED, SAVED, PAYER_ACTION_REQUIRED
+		intent.Status = IntentRequiresAction
+	}
+
+	for _, link := range order.Links {
+		if link.Rel == "approve" || link.Rel == "payer-action" {
+			intent.NextActionURL = link.Href
+		}
+	}
+	return intent
+}
+
+func ppFirstCapture(order *ppOrder) *ppCapture {
+	for i := range order.PurchaseUnits {
+		if captures := order.PurchaseUnits[i].Payments.Captures; len(captures) > 0 {
+			return &captures[0]
+		}
+	}
+	return nil
+}
+
+// ppCaptureIDFromLinks finds the capture a refund belongs to
+func ppCaptureIDFromLinks(links []ppLink) string {
+	for _, link := range links {
+		if link.Rel == "up" {
+			if i := strings.LastIndex(link.Href, "/captures/"); i >= 0 {
+				return link.Href[i+len("/captures/"):]
+			}
+		}
+	}
+	return ""
+}
+
+// ppRefundStatus maps a PayPal refund status onto ours
+func ppRefundStatus(status string) models.RefundStatus {
+	switch status {
+	case "COMPLETED":
+		return models.RefundStatusSucceeded
+	case "CANCELLED", "FAILED":
+		return models.RefundStatusFailed
+	default:
+		return models.RefundStatusPending
+	}
+}
+
+// ppValue formats cents the way PayPal writes amounts
+func ppValue(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+// ppMoney reads an amount as PayPal writes it
+func ppMoney(amount ppAmount) money.Money {
+	value, err := strconv.ParseFloat(amount.Value, 64)
+	if err != nil {
+		value = 0
+	}
+	return money.Money{Cents: money.FromFloat(value).Cents, Currency: strings.ToLower(amount.CurrencyCode)}
+}
@@ -0,0 +1,313 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+)
+
+// paypalStandIn answers the parts of the PayPal REST API the provider uses,
+// keeping orders in memory
+type paypalStandIn struct {
+	t *testing.T
+
+	mu             sync.Mutex
+	tokens         int                        // Access tokens handed out
+	orders         map[string]json.RawMessage // Replies to GET by order ID
+	captureReplies map[string]ppReply         // Replies to capture by order ID
+	refundBodies   []map[string]interface{}
+	verifyStatus   string // Verification status of every webhook
+	verifyBodies   []map[string]interface{}
+	failAll        int // Status every API call but the token fails with, if set
+}
+
+type ppReply struct {
+	status int
+	body   string
+}
+
+func newPayPalStandIn(t *testing.T) (*paypalStandIn, *PayPal) {
+	t.Helper()
+	s := &paypalStandIn{
+		t:              t,
+		orders:         make(map[string]json.RawMessage),
+		captureReplies: make(map[string]ppReply),
+		verifyStatus:   "SUCCESS",
+	}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return s, NewPayPal(configs.PayPalConfig{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		WebhookID:    "WH-1",
+		BaseURL:      server.URL,
+	})
+}
+
+func (s *paypalStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/v1/oauth2/token" {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "client" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.tokens++
+		w.Write([]byte(`{"access_token":"token","expires_in":32400}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.failAll != 0 {
+		w.WriteHeader(s.failAll)
+		return
+	}
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && path == "/v2/checkout/orders":
+		unit := body["purchase_units"].([]interface{})[0].(map[string]interface{})
+		amount, _ := json.Marshal(unit["amount"])
+		order := `{"id":"ORDER-1","status":"CREATED","purchase_units":[{"amount":` + string(amount) + `}],
+			"links":[{"href":"https://paypal.test/checkoutnow?token=ORDER-1","rel":"approve"}]}`
+		s.orders["ORDER-1"] = json.RawMessage(order)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(order))
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v2/checkout/orders/"):
+		order, ok := s.orders[strings.TrimPrefix(path, "/v2/checkout/orders/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"name":"RESOURCE_NOT_FOUND","message":"The specified resource does not exist."}`))
+			return
+		}
+		w.Write(order)
+
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/capture"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/v2/checkout/orders/"), "/capture")
+		reply, ok := s.captureReplies[id]
+		if !ok {
+			s.t.Errorf("unexpected capture of %s", id)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if reply.status < 300 {
+			s.orders[id] = json.RawMessage(reply.body)
+		}
+		w.WriteHeader(reply.status)
+		w.Write([]byte(reply.body))
+
+	case r.Method == http.MethodPost && path == "/v2/payments/captures/CAPTURE-1/refund":
+		s.refundBodies = append(s.refundBodies, body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"REFUND-1","status":"COMPLETED"}`))
+
+	case r.Method == http.MethodPost && path == "/v1/notifications/verify-webhook-signature":
+		s.verifyBodies = append(s.verifyBodies, body)
+		w.Write([]byte(`{"verification_status":"` + s.verifyStatus + `"}`))
+
+	default:
+		s.t.Errorf("unexpected PayPal call %s %s", r.Method, path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+const ppCompletedOrder = `{"id":"ORDER-1","status":"COMPLETED","purchase_units":[{
+	"amount":{"currency_code":"CAD","value":"25.00"},
+	"payments":{"captures":[{"id":"CAPTURE-1","status":"COMPLETED","amount":{"currency_code":"CAD","value":"25.00"}}]}}]}`
+
+func TestPayPalCreateAndCapture(t *testing.T) {
+	standIn, paypal := newPayPalStandIn(t)
+
+	intent, err := paypal.CreateIntent(IntentRequest{
+		Amount:    money.Cents(2500),
+		ReturnURL: "https://wearhouse.test/return",
+		CancelURL: "https://wearhouse.test/cancel",
+		Metadata:  map[string]string{"checkout_id": "checkout-1"},
+	})
+	if err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+	if intent.ID != "ORDER-1" || intent.Status != IntentRequiresAction {
+		t.Errorf("intent = %s %s, want ORDER-1 %s", intent.ID, intent.Status, IntentRequiresAction)
+	}
+	if intent.Amount.Cents != 2500 || intent.Amount.Currency != "cad" {
+		t.Errorf("amount = %+v, want 2500 cad", intent.Amount)
+	}
+	if intent.NextActionURL != "https://paypal.test/checkoutnow?token=ORDER-1" {
+		t.Errorf("next action = %q, want the approve link", intent.NextActionURL)
+	}
+
+	standIn.captureReplies["ORDER-1"] = ppReply{http.StatusCreated, ppCompletedOrder}
+	intent, err = paypal.Confirm("ORDER-1", ConfirmRequest{})
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	if intent.Status != IntentSucceeded || intent.Amount.Cents != 2500 {
+		t.Errorf("captured intent = %s of %d cents, want %s of 2500", intent.Status, intent.Amount.Cents, IntentSucceeded)
+	}
+
+	if standIn.tokens != 1 {
+		t.Errorf("fetched %d access tokens, want 1 reused", standIn.tokens)
+	}
+}
+
+func TestPayPalCaptureErrors(t *testing.T) {
+	declined := `{"name":"UNPROCESSABLE_ENTITY","details":[{"issue":"INSTRUMENT_DECLINED","description":"The instrument presented was declined."}]}`
+	notApproved := `{"name":"UNPROCESSABLE_ENTITY","details":[{"issue":"ORDER_NOT_APPROVED","description":"Payer has not yet approved the Order for payment."}]}`
+
+	tests := []struct {
+		name       string
+		reply      ppReply
+		wantStatus IntentStatus // Empty when Confirm should fail
+		wantIssue  string       // The PayPal issue Confirm should fail with
+	}{
+		{"declined sends the buyer back to PayPal", ppReply{http.StatusUnprocessableEntity, declined}, IntentRequiresAction, ""},
+		{"not approved yet", ppReply{http.StatusUnprocessableEntity, notApproved}, "", "ORDER_NOT_APPROVED"},
+		{"server error without a body", ppReply{http.StatusInternalServerError, ""}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, paypal := newPayPalStandIn(t)
+			if _, err := paypal.CreateIntent(IntentRequest{Amount: money.Cents(2500)}); err != nil {
+				t.Fatalf("CreateIntent: %v", err)
+			}
+			standIn.captureReplies["ORDER-1"] = tt.reply
+
+			intent, err := paypal.Confirm("ORDER-1", ConfirmRequest{})
+			if tt.wantStatus != "" {
+				if err != nil {
+					t.Fatalf("Confirm: %v", err)
+				}
+				if intent.Status != tt.wantStatus || intent.FailureMessage == "" {
+					t.Errorf("intent = %s %q, want %s with a failure message", intent.Status, intent.FailureMessage, tt.wantStatus)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Confirm succeeded with status %d", tt.reply.status)
+			}
+			var ppErr *ppError
+			isPPErr := errors.As(err, &ppErr)
+			if tt.wantIssue != "" && (!isPPErr || ppErr.Details[0].Issue != tt.wantIssue) {
+				t.Errorf("Confirm = %v, want PayPal issue %s", err, tt.wantIssue)
+			}
+			if tt.wantIssue == "" && isPPErr {
+				t.Errorf("Confirm = %v, want an unexpected status error", err)
+			}
+		})
+	}
+}
+
+func TestPayPalRefund(t *testing.T) {
+	standIn, paypal := newPayPalStandIn(t)
+	standIn.orders["ORDER-1"] = json.RawMessage(ppCompletedOrder)
+
+	refund, err := paypal.Refund(RefundRequest{
+		IntentID: "ORDER-1",
+		Amount:   money.Cents(1050),
+		Metadata: map[string]string{"order_id": "order-1"},
+	})
+	if err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if refund.ID != "REFUND-1" || refund.Status != models.RefundStatusSucceeded {
+		t.Errorf("refund = %+v, want REFUND-1 succeeded", refund)
+	}
+
+	if len(standIn.refundBodies) != 1 {
+		t.Fatalf("sent %d refunds, want 1", len(standIn.refundBodies))
+	}
+	amount := standIn.refundBodies[0]["amount"].(map[string]interface{})
+	if amount["value"] != "10.50" || amount["currency_code"] != "CAD" {
+		t.Errorf("refunded %v %v, want 10.50 CAD", amount["value"], amount["currency_code"])
+	}
+}
+
+func TestPayPalRefundUncapturedOrder(t *testing.T) {
+	standIn, paypal := newPayPalStandIn(t)
+	if _, err := paypal.CreateIntent(IntentRequest{Amount: money.Cents(2500)}); err != nil {
+		t.Fatalf("CreateIntent: %v", err)
+	}
+
+	if _, err := paypal.Refund(RefundRequest{IntentID: "ORDER-1", Amount: money.Cents(2500)}); err == nil {
+		t.Error("refunded an order that was never captured")
+	}
+	if len(standIn.refundBodies) != 0 {
+		t.Errorf("sent %d refunds, want none", len(standIn.refundBodies))
+	}
+}
+
+func TestPayPalParseWebhook(t *testing.T) {
+	payload := []byte(`{"id":"WH-EVT-1","event_type":"PAYMENT.CAPTURE.COMPLETED","resource":{
+		"id":"CAPTURE-1","status":"COMPLETED","amount":{"currency_code":"CAD","value":"25.00"},
+		"supplementary_data":{"related_ids":{"order_id":"ORDER-1"}}}}`)
+	header := http.Header{}
+	header.Set("Paypal-Auth-Algo", "SHA256withRSA")
+	header.Set("Paypal-Cert-Url", "https://api.paypal.test/cert")
+	header.Set("Paypal-Transmission-Id", "transmission-1")
+	header.Set("Paypal-Transmission-Sig", "signature")
+	header.Set("Paypal-Transmission-Time", "2026-03-02T10:00:00Z")
+
+	t.Run("verified", func(t *testing.T) {
+		standIn, paypal := newPayPalStandIn(t)
+		event, err := paypal.ParseWebhook(payload, header)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		if event.Type != EventPaymentSucceeded || event.Intent == nil || event.Intent.ID != "ORDER-1" || event.Intent.Amount.Cents != 2500 {
+			t.Errorf("event = %+v, want ORDER-1 paid 2500 cents", event)
+		}
+
+		if len(standIn.verifyBodies) != 1 {
+			t.Fatalf("verified %d times, want 1", len(standIn.verifyBodies))
+		}
+		body := standIn.verifyBodies[0]
+		if body["webhook_id"] != "WH-1" || body["transmission_id"] != "transmission-1" || body["transmission_sig"] != "signature" {
+			t.Errorf("verification request = %v, want the webhook ID and transmission headers", body)
+		}
+	})
+
+	t.Run("signature check failed", func(t *testing.T) {
+		standIn, paypal := newPayPalStandIn(t)
+		standIn.verifyStatus = "FAILURE"
+		if _, err := paypal.ParseWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("ParseWebhook = %v, want ErrInvalidSignature", err)
+		}
+	})
+
+	t.Run("PayPal unavailable", func(t *testing.T) {
+		standIn, paypal := newPayPalStandIn(t)
+		standIn.failAll = http.StatusServiceUnavailable
+		_, err := paypal.ParseWebhook(payload, header)
+		if err == nil || errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("ParseWebhook = %v, want an error that isn't a bad signature", err)
+		}
+	})
+}
+
+func TestPayPalAuthenticationFailure(t *testing.T) {
+	_, paypal := newPayPalStandIn(t)
+	paypal.config.ClientSecret = "wrong"
+	if _, err := paypal.CreateIntent(IntentRequest{Amount: money.Cents(2500)}); err == nil {
+		t.Error("CreateIntent succeeded with the wrong credentials")
+	}
+}
@@ -0,0 +1,159 @@
+package payments
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+)
+
+// ErrInvalidSignature is returned when a webhook can't be shown to come from
+// the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Provider is a payment processor buyers can pay through
+type Provider interface {
+	// Name identifies the provider on payments and in its webhook URL
+	Name() string
+	// CreateIntent starts collecting a payment
+	CreateIntent(req IntentRequest) (*Intent, error)
+	// GetIntent returns the current state of a payment
+	GetIntent(id string) (*Intent, error)
+	// CancelIntent abandons a payment that hasn't gone through
+	CancelIntent(id string) error
+	// Confirm charges a payment method against a payment
+	Confirm(id string, req ConfirmRequest) (*Intent, error)
+	// Refund returns some or all of a successful payment
+	Refund(req RefundRequest) (*Refund, error)
+	// ParseWebhook checks a webhook came from the provider and decodes it
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+	// DecodeEvent decodes a webhook payload that was already verified
+	DecodeEvent(payload []byte) (*Event, error)
+}
+
+type IntentStatus string
+
+const (
+	IntentRequiresPaymentMethod IntentStatus = "requires_payment_method"
+	IntentRequiresConfirmation  IntentStatus = "requires_confirmation"
+	IntentRequiresAction        IntentStatus = "requires_action" // The buyer must authenticate, e.g. 3D Secure
+	IntentProcessing            IntentStatus = "processing"
+	IntentSucceeded             IntentStatus = "succeeded"
+	IntentCanceled              IntentStatus = "canceled"
+)
+
+// Payable reports whether the buyer can still pay the intent as it is
+func (s IntentStatus) Payable() bool {
+	switch s {
+	case IntentRequiresPaymentMethod, IntentRequiresConfirmation, IntentRequiresAction:
+		return true
+	}
+	return false
+}
+
+// IntentRequest is what a provider needs to start a payment
+type IntentRequest struct {
+	Amount      money.Money
+	Description string
+	Metadata    map[string]string
+	ReturnURL   string // Where a provider that redirects the buyer sends them back to
+	CancelURL   string // Where it sends them when they give up
+}
+
+// Intent is a payment as the provider sees it
+type Intent struct {
+	ID             string       `json:"id"`
+	ClientSecret   string       `json:"client_secret,omitempty"` // Lets the client finish the payment
+	Amount         money.Money  `json:"amount"`
+	Status         IntentStatus `json:"status"`
+	NextActionURL  string       `json:"next_action_url,omitempty"` // Where to send the buyer to authenticate
+	FailureMessage string       `json:"failure_message,omitempty"`
+}
+
+// ConfirmRequest is the payment method to charge and where to send the
+// buyer back to if they have to authenticate
+type ConfirmRequest struct {
+	PaymentMethod string
+	ReturnURL     string
+}
+
+// RefundRequest refunds part or all of a payment
+type RefundRequest struct {
+	IntentID string
+	Amount   money.Money
+	Metadata map[string]string
+}
+
+// Refund is money returned against a payment
+type Refund struct {
+	ID     string              `json:"id"`
+	Status models.RefundStatus `json:"status"`
+}
+
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	EventPaymentCanceled  EventType = "payment.canceled"
+	EventRefundUpdated    EventType = "refund.updated"
+	EventDisputeUpdated   EventType = "dispute.updated"
+)
+
+// Event is a webhook translated out of the provider's own format. Type is
+// empty for events we don't act on.
+type Event struct {
+	ID      string        `json:"id"`
+	Type    EventType     `json:"type"`
+	RawType string        `json:"raw_type"` // The provider's name for the event
+	Intent  *Intent       `json:"intent,omitempty"`
+	Refunds *RefundUpdate `json:"refunds,omitempty"`
+	Dispute *Dispute      `json:"dispute,omitempty"`
+}
+
+// RefundUpdate is the state of every refund against a payment
+type RefundUpdate struct {
+	IntentID      string   `json:"intent_id"`
+	Refunds       []Refund `json:"refunds"`
+	FullyRefunded bool     `json:"fully_refunded"`
+}
+
+// Dispute is a chargeback the buyer opened with their bank
+type Dispute struct {
+	ID       string      `json:"id"`
+	IntentID string      `json:"intent_id"`
+	Amount   money.Money `json:"amount"`
+	Reason   string      `json:"reason"`
+	Status   string      `json:"status"`
+	Closed   bool        `json:"closed"`
+}
+
+var (
+	fakesMu sync.Mutex
+	fakes   = make(map[configs.FakePaymentsConfig]*Fake)
+)
+
+// New returns the providers configured for this deployment, by the payment
+// method they take. The fake stands in for every provider. It only keeps
+// payments in memory, so every call with the same settings shares one.
+func New(config *configs.Config) map[string]Provider {
+	if config.PaymentProvider == "fake" {
+		fakesMu.Lock()
+		fake, ok := fakes[config.FakePayments]
+		if !ok {
+			fake = NewFake(config.FakePayments)
+			fakes[config.FakePayments] = fake
+		}
+		fakesMu.Unlock()
+		return map[string]Provider{
+			models.PaymentMethodCreditCard: fake,
+			models.PaymentMethodPayPal:     fake,
+		}
+	}
+	return map[string]Provider{
+		models.PaymentMethodCreditCard: NewStripe(config.StripeSecretKey, config.StripeWebhookSecret),
+		models.PaymentMethodPayPal:     NewPayPal(config.PayPal),
+	}
+}
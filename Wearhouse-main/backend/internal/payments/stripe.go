@@ -0,0 +1,207 @@
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"wearhouse/internal/models"
+	"wearhouse/internal/money"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/client"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// Stripe takes card payments through Stripe payment intents
+type Stripe struct {
+	api           *client.API
+	webhookSecret string
+}
+
+func NewStripe(secretKey, webhookSecret string) *Stripe {
+	api := &client.API{}
+	api.Init(secretKey, nil)
+	return &Stripe{
+		api:           api,
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+func (s *Stripe) CreateIntent(req IntentRequest) (*Intent, error) {
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(req.Amount.Cents),
+		Currency: stripe.String(req.Amount.Currency),
+		Metadata: req.Metadata,
+	}
+	if req.Description != "" {
+		params.Description = stripe.String(req.Description)
+	}
+
+	pi, err := s.api.PaymentIntents.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (s *Stripe) GetIntent(id string) (*Intent, error) {
+	pi, err := s.api.PaymentIntents.Get(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (s *Stripe) CancelIntent(id string) error {
+	_, err := s.api.PaymentIntents.Cancel(id, nil)
+	return err
+}
+
+func (s *Stripe) Confirm(id string, req ConfirmRequest) (*Intent, error) {
+	params := &stripe.PaymentIntentConfirmParams{}
+	if req.PaymentMethod != "" {
+		params.PaymentMethod = stripe.String(req.PaymentMethod)
+	}
+	if req.ReturnURL != "" {
+		params.ReturnURL = stripe.String(req.ReturnURL)
+	}
+
+	pi, err := s.api.PaymentIntents.Confirm(id, params)
+	if err != nil {
+		// A declined card still leaves the intent behind to report on
+		if stripeErr, ok := err.(*stripe.Error); ok && stripeErr.PaymentIntent != nil {
+			return stripeIntent(stripeErr.PaymentIntent), nil
+		}
+		return nil, err
+	}
+	return stripeIntent(pi), nil
+}
+
+func (s *Stripe) Refund(req RefundRequest) (*Refund, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(req.IntentID),
+		Amount:        stripe.Int64(req.Amount.Cents),
+		Metadata:      req.Metadata,
+	}
+
+	re, err := s.api.Refunds.New(params)
+	if err != nil {
+		return nil, err
+	}
+	return &Refund{ID: re.ID, Status: stripeRefundStatus(re.Status)}, nil
+}
+
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if err := webhook.ValidatePayload(payload, header.Get("Stripe-Signature"), s.webhookSecret); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return s.DecodeEvent(payload)
+}
+
+func (s *Stripe) DecodeEvent(payload []byte) (*Event, error) {
+	var raw stripe.Event
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid stripe event: %w", err)
+	}
+
+	event := &Event{ID: raw.ID, RawType: string(raw.Type)}
+	switch {
+	case raw.Type == "payment_intent.succeeded",
+		raw.Type == "payment_intent.payment_failed",
+		raw.Type == "payment_intent.canceled":
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(raw.Data.Raw, &pi); err != nil {
+			return nil, fmt.Errorf("invalid payment intent data: %w", err)
+		}
+		event.Intent = stripeIntent(&pi)
+		switch raw.Type {
+		case "payment_intent.succeeded":
+			event.Type = EventPaymentSucceeded
+		case "payment_intent.payment_failed":
+			event.Type = EventPaymentFailed
+		default:
+			event.Type = EventPaymentCanceled
+		}
+
+	case raw.Type == "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(raw.Data.Raw, &charge); err != nil {
+			return nil, fmt.Errorf("invalid charge data: %w", err)
+		}
+		if charge.PaymentIntent == nil {
+			return event, nil
+		}
+		update := &RefundUpdate{
+			IntentID:      charge.PaymentIntent.ID,
+			FullyRefunded: charge.Refunded || charge.AmountRefunded >= charge.Amount,
+		}
+		if charge.Refunds != nil {
+			for _, re := range charge.Refunds.Data {
+				update.Refunds = append(update.Refunds, Refund{ID: re.ID, Status: stripeRefundStatus(re.Status)})
+			}
+		}
+		event.Type = EventRefundUpdated
+		event.Refunds = update
+
+	case strings.HasPrefix(string(raw.Type), "charge.dispute."):
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(raw.Data.Raw, &dispute); err != nil {
+			return nil, fmt.Errorf("invalid dispute data: %w", err)
+		}
+		var intentID string
+		switch {
+		case dispute.PaymentIntent != nil:
+			intentID = dispute.PaymentIntent.ID
+		case dispute.Charge != nil && dispute.Charge.PaymentIntent != nil:
+			intentID = dispute.Charge.PaymentIntent.ID
+		default:
+			return nil, fmt.Errorf("dispute %s has no payment intent", dispute.ID)
+		}
+		event.Type = EventDisputeUpdated
+		event.Dispute = &Dispute{
+			ID:       dispute.ID,
+			IntentID: intentID,
+			Amount:   money.Money{Cents: dispute.Amount, Currency: string(dispute.Currency)},
+			Reason:   string(dispute.Reason),
+			Status:   string(dispute.Status),
+			Closed: dispute.Status == stripe.DisputeStatusWon ||
+				dispute.Status == stripe.DisputeStatusLost ||
+				dispute.Status == stripe.DisputeStatusWarningClosed,
+		}
+	}
+
+	return event, nil
+}
+
+func stripeIntent(pi *stripe.PaymentIntent) *Intent {
+	intent := &Intent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Amount:       money.Money{Cents: pi.Amount, Currency: string(pi.Currency)},
+		Status:       IntentStatus(pi.Status),
+	}
+	if pi.NextAction != nil && pi.NextAction.RedirectToURL != nil {
+		intent.NextActionURL = pi.NextAction.RedirectToURL.URL
+	}
+	if pi.LastPaymentError != nil {
+		intent.FailureMessage = pi.LastPaymentError.Msg
+	}
+	return intent
+}
+
+// stripeRefundStatus maps a Stripe refund status onto ours
+func stripeRefundStatus(status stripe.RefundStatus) models.RefundStatus {
+	switch status {
+	case stripe.RefundStatusSucceeded:
+		return models.RefundStatusSucceeded
+	case stripe.RefundStatusFailed, stripe.RefundStatusCanceled:
+		return models.RefundStatusFailed
+	default:
+		return models.RefundStatusPending
+	}
+}
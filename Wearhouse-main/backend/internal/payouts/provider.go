@@ -0,0 +1,52 @@
+package payouts
+
+import (
+	"errors"
+	"wearhouse/configs"
+	"wearhouse/internal/money"
+)
+
+// ErrRejected is returned, wrapped, when the provider refused a transfer, so
+// no money moved. Any other transfer error may or may not have paid out.
+var ErrRejected = errors.New("transfer rejected")
+
+// Provider is a service that moves sellers' earnings to their bank accounts
+type Provider interface {
+	// Name identifies the provider on payouts
+	Name() string
+	// CreateAccount opens an account for a seller to be paid into
+	CreateAccount(req AccountRequest) (string, error)
+	// OnboardingURL is where the seller goes to finish setting up their
+	// account, e.g. to add their bank details
+	OnboardingURL(accountID, returnURL, refreshURL string) (string, error)
+	// Transfer pays money out of the platform to a seller's account. It
+	// fails with ErrRejected if the transfer definitely wasn't made.
+	Transfer(req TransferRequest) (*Transfer, error)
+}
+
+// AccountRequest is what a provider needs to open a seller's account
+type AccountRequest struct {
+	Email   string
+	Country string // ISO 3166-1 alpha-2
+}
+
+// TransferRequest pays a seller
+type TransferRequest struct {
+	AccountID      string
+	Amount         money.Money
+	IdempotencyKey string // The same key never pays twice
+	Metadata       map[string]string
+}
+
+// Transfer is money sent to a seller
+type Transfer struct {
+	ID string
+}
+
+// New returns the payout provider configured for this deployment
+func New(config *configs.Config) Provider {
+	if config.PayoutProvider == "fake" {
+		return NewFake()
+	}
+	return NewStripe(config.StripeSecretKey)
+}
@@ -0,0 +1,76 @@
+package payouts
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/client"
+)
+
+// Stripe pays sellers through Stripe Connect. Each seller gets an Express
+// account, and transfers move money from our balance to theirs.
+type Stripe struct {
+	api *client.API
+}
+
+func NewStripe(secretKey string) *Stripe {
+	api := &client.API{}
+	api.Init(secretKey, nil)
+	return &Stripe{api: api}
+}
+
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+func (s *Stripe) CreateAccount(req AccountRequest) (string, error) {
+	account, err := s.api.Accounts.New(&stripe.AccountParams{
+		Type:    stripe.String(string(stripe.AccountTypeExpress)),
+		Country: stripe.String(req.Country),
+		Email:   stripe.String(req.Email),
+		Capabilities: &stripe.AccountCapabilitiesParams{
+			Transfers: &stripe.AccountCapabilitiesTransfersParams{Requested: stripe.Bool(true)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return account.ID, nil
+}
+
+func (s *Stripe) OnboardingURL(accountID, returnURL, refreshURL string) (string, error) {
+	link, err := s.api.AccountLinks.New(&stripe.AccountLinkParams{
+		Account:    stripe.String(accountID),
+		ReturnURL:  stripe.String(returnURL),
+		RefreshURL: stripe.String(refreshURL),
+		Type:       stripe.String("account_onboarding"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return link.URL, nil
+}
+
+func (s *Stripe) Transfer(req TransferRequest) (*Transfer, error) {
+	params := &stripe.TransferParams{
+		Amount:      stripe.Int64(req.Amount.Cents),
+		Currency:    stripe.String(req.Amount.Currency),
+		Destination: stripe.String(req.AccountID),
+		Metadata:    req.Metadata,
+	}
+	params.SetIdempotencyKey(req.IdempotencyKey)
+
+	transfer, err := s.api.Transfers.New(params)
+	if err != nil {
+		// Stripe answered and turned the transfer down. Anything else, e.g.
+		// a timeout, may have gone through.
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) &&
+			(stripeErr.Type == stripe.ErrorTypeCard || stripeErr.Type == stripe.ErrorTypeInvalidRequest) {
+			return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+		}
+		return nil, err
+	}
+	return &Transfer{ID: transfer.ID}, nil
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupAddressRoutes sets up the address book routes
+func SetupAddressRoutes(app *fiber.App, config *configs.Config) {
+	addressHandler := handlers.NewAddressHandler(config)
+
+	addresses := app.Group("/api/addresses")
+
+	// Protected routes (require authentication)
+	addresses.Use(middleware.AuthMiddleware())
+	addresses.Get("/", addressHandler.GetAddresses)
+	addresses.Post("/", addressHandler.CreateAddress)
+	addresses.Get("/:id", addressHandler.GetAddress)
+	addresses.Put("/:id", addressHandler.UpdateAddress)
+	addresses.Delete("/:id", addressHandler.DeleteAddress)
+}
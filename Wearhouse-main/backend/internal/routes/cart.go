@@ -20,4 +20,7 @@ func SetupCartRoutes(app *fiber.App, config interface{}) {
 	cart.Put("/items/:id", handlers.UpdateCartItem)
 	cart.Delete("/items/:id", handlers.RemoveFromCart)
 	cart.Delete("/", handlers.ClearCart)
+	cart.Post("/validate", handlers.ValidateCart)
+	cart.Post("/coupon", handlers.ApplyCoupon)
+	cart.Delete("/coupon", handlers.RemoveCoupon)
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupCouponRoutes sets up the admin routes for coupons
+func SetupCouponRoutes(app *fiber.App, config *configs.Config) {
+	couponHandler := handlers.NewCouponHandler(config)
+
+	admin := app.Group("/api/admin/coupons")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Get("/", couponHandler.GetCoupons)
+	admin.Post("/", couponHandler.CreateCoupon)
+	admin.Delete("/:id", couponHandler.DeactivateCoupon)
+}
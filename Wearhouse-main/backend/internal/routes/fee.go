@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupFeeRoutes sets up the admin routes for platform fees
+func SetupFeeRoutes(app *fiber.App, config *configs.Config) {
+	feeHandler := handlers.NewFeeHandler(config)
+
+	admin := app.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Get("/fee-schedules", feeHandler.GetFeeSchedules)
+	admin.Post("/fee-schedules", feeHandler.CreateFeeSchedule)
+	admin.Put("/users/:id/seller-tier", feeHandler.UpdateSellerTier)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupMeetupRoutes sets up the campus meetup spot routes
+func SetupMeetupRoutes(app *fiber.App, config *configs.Config) {
+	meetupHandler := handlers.NewMeetupHandler(config)
+
+	spots := app.Group("/api/meetup-spots")
+
+	// Protected routes (require authentication)
+	spots.Use(middleware.AuthMiddleware())
+	spots.Get("/", meetupHandler.GetMeetupSpots)
+
+	// Admin routes
+	admin := app.Group("/api/admin/meetup-spots")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Get("/", meetupHandler.GetAllMeetupSpots)
+	admin.Post("/", meetupHandler.CreateMeetupSpot)
+	admin.Put("/:id", meetupHandler.UpdateMeetupSpot)
+	admin.Delete("/:id", meetupHandler.DeleteMeetupSpot)
+}
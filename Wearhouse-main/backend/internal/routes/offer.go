@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupOfferRoutes sets up all offer-related routes
+func SetupOfferRoutes(app *fiber.App, config *configs.Config) {
+	offerHandler := handlers.NewOfferHandler(config)
+
+	offers := app.Group("/api/offers")
+
+	// Protected routes (require authentication)
+	offers.Use(middleware.AuthMiddleware())
+	offers.Post("/", offerHandler.CreateOffer)
+	offers.Get("/", offerHandler.GetOffers)
+	offers.Get("/:id", offerHandler.GetOffer)
+	offers.Post("/:id/accept", offerHandler.AcceptOffer)
+	offers.Post("/:id/decline", offerHandler.DeclineOffer)
+	offers.Post("/:id/counter", offerHandler.CounterOffer)
+	offers.Post("/:id/withdraw", offerHandler.WithdrawOffer)
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"wearhouse/configs"
 	"wearhouse/internal/handlers"
 	"wearhouse/internal/middleware"
 
@@ -8,13 +9,29 @@ import (
 )
 
 // SetupOrderRoutes sets up all order-related routes
-func SetupOrderRoutes(app *fiber.App, config interface{}) {
+func SetupOrderRoutes(app *fiber.App, config *configs.Config, paymentHandler *handlers.PaymentHandler) {
+	orderHandler := handlers.NewOrderHandler(config, paymentHandler)
+
 	orders := app.Group("/api/orders")
 
 	// Protected routes (require authentication)
 	orders.Use(middleware.AuthMiddleware())
-	orders.Post("/", handlers.CreateOrder)
-	orders.Get("/", handlers.GetOrders)
-	orders.Get("/:id", handlers.GetOrder)
-	orders.Put("/:id/status", handlers.UpdateOrderStatus)
+	orders.Post("/", middleware.IdempotencyMiddleware(config.IdempotencyRetention), orderHandler.CreateOrder)
+	orders.Get("/", orderHandler.GetOrders)
+	orders.Get("/:id", orderHandler.GetOrder)
+	orders.Put("/:id/status", orderHandler.UpdateOrderStatus)
+	orders.Post("/:id/cancel", orderHandler.CancelOrder)
+	orders.Get("/:id/receipt", orderHandler.GetReceipt)
+	orders.Get("/:id/handoff", orderHandler.GetHandoffCode)
+
+	// Meetup orders, for both the buyer and the seller
+	orders.Post("/:id/meetup/proposals", orderHandler.ProposeMeetup)
+	orders.Post("/:id/meetup/proposals/:proposalId/accept", orderHandler.AcceptMeetup)
+	orders.Post("/:id/meetup/proposals/:proposalId/decline", orderHandler.DeclineMeetup)
+
+	// Checkouts pay for one order per seller at once
+	checkouts := app.Group("/api/checkouts")
+	checkouts.Use(middleware.AuthMiddleware())
+	checkouts.Post("/", middleware.IdempotencyMiddleware(config.IdempotencyRetention), orderHandler.CreateCheckout)
+	checkouts.Get("/:id", orderHandler.GetCheckout)
 }
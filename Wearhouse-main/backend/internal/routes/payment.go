@@ -1,18 +1,28 @@
 package routes
 
 import (
+	"wearhouse/configs"
 	"wearhouse/internal/handlers"
 	"wearhouse/internal/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupPaymentRoutes(app *fiber.App, paymentHandler *handlers.PaymentHandler) {
+func SetupPaymentRoutes(app *fiber.App, config *configs.Config, paymentHandler *handlers.PaymentHandler) {
 	payments := app.Group("/api/payments")
 
 	// Protected routes (require authentication)
-	payments.Post("/create-intent", middleware.AuthMiddleware(), paymentHandler.CreatePaymentIntent)
+	payments.Post("/create-intent", middleware.AuthMiddleware(),
+		middleware.IdempotencyMiddleware(config.IdempotencyRetention), paymentHandler.CreatePaymentIntent)
 
-	// Webhook route (no authentication required as it's called by Stripe)
-	app.Post("/api/webhook/stripe", paymentHandler.HandleWebhook)
+	payments.Post("/:id/confirm", middleware.AuthMiddleware(), paymentHandler.ConfirmPayment)
+
+	// Webhook route (no authentication required as it's called by the
+	// payment provider, e.g. /api/webhook/stripe)
+	app.Post("/api/webhook/:provider", paymentHandler.HandleWebhook)
+
+	// Admin routes
+	admin := app.Group("/api/admin/webhook-events")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Post("/:id/replay", paymentHandler.ReplayWebhookEvent)
 }
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupSalesRoutes sets up the seller's view of orders for their products
+func SetupSalesRoutes(app *fiber.App, config *configs.Config, paymentHandler *handlers.PaymentHandler) {
+	orderHandler := handlers.NewOrderHandler(config, paymentHandler)
+
+	sales := app.Group("/api/sales")
+
+	// Protected routes (require authentication)
+	sales.Use(middleware.AuthMiddleware())
+	sales.Get("/", orderHandler.GetSales)
+	sales.Get("/:id", orderHandler.GetSale)
+	sales.Post("/:id/ship", orderHandler.MarkShipped)
+	sales.Post("/:id/shipping-rates", orderHandler.GetShippingRates)
+	sales.Post("/:id/shipment", orderHandler.CreateShipment)
+	sales.Post("/:id/ready-for-pickup", orderHandler.MarkReadyForPickup)
+	sales.Post("/:id/cancel", orderHandler.CancelSale)
+	sales.Post("/:id/handoff", orderHandler.ConfirmHandoff)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"wearhouse/configs"
+	"wearhouse/internal/handlers"
+	"wearhouse/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupTaxRoutes sets up the admin routes for sales tax rates
+func SetupTaxRoutes(app *fiber.App, config *configs.Config) {
+	taxHandler := handlers.NewTaxHandler(config)
+
+	admin := app.Group("/api/admin/tax-rates")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Get("/", taxHandler.GetTaxRates)
+	admin.Post("/", taxHandler.CreateTaxRate)
+}
@@ -2,22 +2,41 @@ package routes
 
 import (
 	"wearhouse/configs"
+	"wearhouse/internal/handlers"
 	"wearhouse/internal/middleware"
+	"wearhouse/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // SetupUserRoutes sets up all user-related routes
 func SetupUserRoutes(app *fiber.App, config *configs.Config) {
+	payoutHandler := handlers.NewPayoutHandler(config)
+	walletHandler := handlers.NewWalletHandler(config)
+	userHandler := handlers.NewUserHandler(config)
+
 	users := app.Group("/api/users")
 
 	// Protected routes (require authentication)
 	users.Use(middleware.AuthMiddleware())
 	users.Get("/me", func(c *fiber.Ctx) error {
-		// Get user ID from context (set by middleware)
-		userID := c.Locals("user_id").(string)
+		// Get user from context (set by middleware)
+		claims := c.Locals("user").(*utils.JWTClaims)
 		return c.JSON(fiber.Map{
-			"user_id": userID,
+			"user_id": claims.UserID,
 		})
 	})
+
+	// Seller earnings and payouts
+	users.Get("/me/balance", payoutHandler.GetBalance)
+	users.Post("/me/payout-account", payoutHandler.CreatePayoutAccount)
+
+	// Store credit
+	users.Get("/me/wallet", walletHandler.GetWallet)
+	users.Post("/me/wallet/earnings", walletHandler.DepositEarnings)
+
+	// Admin moderation
+	admin := app.Group("/api/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+	admin.Put("/users/:id/suspension", userHandler.UpdateSuspension)
 }
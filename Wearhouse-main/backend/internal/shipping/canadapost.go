@@ -0,0 +1,283 @@
+package shipping
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+)
+
+// CanadaPost buys labels and tracks parcels through the Canada Post REST
+// web services, as a non-contract (Solutions for Small Business) customer
+type CanadaPost struct {
+	config configs.CanadaPostConfig
+	client *http.Client
+}
+
+func NewCanadaPost(config configs.CanadaPostConfig) *CanadaPost {
+	return &CanadaPost{
+		config: config,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (cp *CanadaPost) Name() string {
+	return "canadapost"
+}
+
+type cpParcel struct {
+	Weight     string        `xml:"weight"` // kg
+	Dimensions *cpDimensions `xml:"dimensions,omitempty"`
+}
+
+type cpDimensions struct {
+	Length string `xml:"length"`
+	Width  string `xml:"width"`
+	Height string `xml:"height"`
+}
+
+type cpMailingScenario struct {
+	XMLName        xml.Name `xml:"http://www.canadapost.ca/ws/ship/rate-v4 mailing-scenario"`
+	CustomerNumber string   `xml:"customer-number"`
+	Parcel         cpParcel `xml:"parcel-characteristics"`
+	OriginPostal   string   `xml:"origin-postal-code"`
+	DestPostal     string   `xml:"destination>domestic>postal-code"`
+}
+
+type cpPriceQuotes struct {
+	Quotes []struct {
+		ServiceCode string `xml:"service-code"`
+		ServiceName string `xml:"service-name"`
+		Due         string `xml:"price-details>due"`
+		TransitTime string `xml:"service-standard>expected-transit-time"`
+	} `xml:"price-quote"`
+}
+
+// Rates quotes the domestic services available between two postal codes
+func (cp *CanadaPost) Rates(req RateRequest) ([]Rate, error) {
+	scenario := cpMailingScenario{
+		CustomerNumber: cp.config.CustomerNumber,
+		Parcel:         toCPParcel(req.Parcel),
+		OriginPostal:   cpPostalCode(req.From.PostalCode),
+		DestPostal:     cpPostalCode(req.To.PostalCode),
+	}
+
+	var quotes cpPriceQuotes
+	if err := cp.do(http.MethodPost, "/rs/ship/price", "application/vnd.cpc.ship.rate-v4+xml", scenario, &quotes); err != nil {
+		return nil, err
+	}
+
+	rates := make([]Rate, 0, len(quotes.Quotes))
+	for _, q := range quotes.Quotes {
+		price, err := strconv.ParseFloat(q.Due, 64)
+		if err != nil {
+			return nil, fmt.Errorf("canada post: invalid price %q for %s", q.Due, q.ServiceCode)
+		}
+		days, _ := strconv.Atoi(q.TransitTime)
+		rates = append(rates, Rate{
+			ServiceCode: q.ServiceCode,
+			ServiceName: q.ServiceName,
+			Price:       price,
+			Currency:    "CAD",
+			TransitDays: days,
+		})
+	}
+
+	return rates, nil
+}
+
+type cpAddressDetails struct {
+	AddressLine1 string `xml:"address-line-1"`
+	City         string `xml:"city"`
+	ProvState    string `xml:"prov-state"`
+	CountryCode  string `xml:"country-code,omitempty"`
+	PostalCode   string `xml:"postal-zip-code"`
+}
+
+type cpShipment struct {
+	XMLName       xml.Name `xml:"http://www.canadapost.ca/ws/ncshipment-v4 non-contract-shipment"`
+	ShippingPoint string   `xml:"requested-shipping-point"`
+	DeliverySpec  struct {
+		ServiceCode string `xml:"service-code"`
+		Sender      struct {
+			Company string           `xml:"company"`
+			Phone   string           `xml:"contact-phone"`
+			Address cpAddressDetails `xml:"address-details"`
+		} `xml:"sender"`
+		Destination struct {
+			Name    string           `xml:"name"`
+			Phone   string           `xml:"client-voice-number,omitempty"`
+			Address cpAddressDetails `xml:"address-details"`
+		} `xml:"destination"`
+		Parcel      cpParcel `xml:"parcel-characteristics"`
+		Preferences struct {
+			ShowPackingInstructions bool `xml:"show-packing-instructions"`
+		} `xml:"preferences"`
+		Reference string `xml:"references>customer-ref-1,omitempty"`
+	} `xml:"delivery-spec"`
+}
+
+type cpShipmentInfo struct {
+	ShipmentID  string `xml:"shipment-id"`
+	TrackingPin string `xml:"tracking-pin"`
+	Links       []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"links>link"`
+}
+
+// CreateLabel creates a non-contract shipment. The label itself is a PDF
+// behind the returned link.
+func (cp *CanadaPost) CreateLabel(req LabelRequest) (*Label, error) {
+	var shipment cpShipment
+	shipment.ShippingPoint = cpPostalCode(req.From.PostalCode)
+	spec := &shipment.DeliverySpec
+	spec.ServiceCode = req.ServiceCode
+	spec.Sender.Company = req.From.Name
+	spec.Sender.Phone = req.From.Phone
+	spec.Sender.Address = toCPAddress(req.From)
+	spec.Destination.Name = req.To.Name
+	spec.Destination.Phone = req.To.Phone
+	spec.Destination.Address = toCPAddress(req.To)
+	spec.Destination.Address.CountryCode = "CA"
+	spec.Parcel = toCPParcel(req.Parcel)
+	spec.Preferences.ShowPackingInstructions = true
+	spec.Reference = req.Reference
+
+	var info cpShipmentInfo
+	path := "/rs/" + cp.config.CustomerNumber + "/ncshipment"
+	if err := cp.do(http.MethodPost, path, "application/vnd.cpc.ncshipment-v4+xml", shipment, &info); err != nil {
+		return nil, err
+	}
+
+	label := &Label{TrackingNumber: info.TrackingPin}
+	for _, link := range info.Links {
+		if link.Rel == "label" {
+			label.LabelURL = link.Href
+		}
+	}
+
+	return label, nil
+}
+
+type cpTrackingSummary struct {
+	Pins []struct {
+		EventType        string `xml:"event-type"`
+		EventDescription string `xml:"event-description"`
+		DeliveryDate     string `xml:"actual-delivery-date"`
+	} `xml:"pin-summary"`
+}
+
+// Track reads the tracking summary of a parcel
+func (cp *CanadaPost) Track(trackingNumber string) (*TrackingStatus, error) {
+	var summary cpTrackingSummary
+	path := "/vis/track/pin/" + trackingNumber + "/summary"
+	if err := cp.do(http.MethodGet, path, "application/vnd.cpc.track-v2+xml", nil, &summary); err != nil {
+		return nil, err
+	}
+	if len(summary.Pins) == 0 {
+		return nil, ErrNotFound
+	}
+
+	pin := summary.Pins[0]
+	status := &TrackingStatus{State: TrackingInTransit, Description: pin.EventDescription}
+	switch {
+	case pin.DeliveryDate != "":
+		status.State = TrackingDelivered
+		if deliveredAt, err := time.Parse("2006-01-02", pin.DeliveryDate); err == nil {
+			status.DeliveredAt = &deliveredAt
+		}
+	case pin.EventType == "" || pin.EventType == "INDUCTION":
+		status.State = TrackingLabelCreated
+	case strings.Contains(pin.EventType, "RETURN") || strings.Contains(pin.EventType, "ATTEMPTED"):
+		status.State = TrackingException
+	}
+
+	return status, nil
+}
+
+type cpMessages struct {
+	Messages []struct {
+		Code        string `xml:"code"`
+		Description string `xml:"description"`
+	} `xml:"message"`
+}
+
+// do sends a request to the web services and decodes the XML reply into out
+func (cp *CanadaPost) do(method, path, mediaType string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := xml.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(append([]byte(xml.Header), payload...))
+	}
+
+	req, err := http.NewRequest(method, cp.config.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(cp.config.Username, cp.config.Password)
+	req.Header.Set("Accept", mediaType)
+	req.Header.Set("Accept-Language", "en-CA")
+	if in != nil {
+		req.Header.Set("Content-Type", mediaType)
+	}
+
+	resp, err := cp.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("canada post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("canada post: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var messages cpMessages
+		if xml.Unmarshal(data, &messages) == nil && len(messages.Messages) > 0 {
+			return fmt.Errorf("canada post: %s %s", messages.Messages[0].Code, messages.Messages[0].Description)
+		}
+		return fmt.Errorf("canada post: unexpected status %d", resp.StatusCode)
+	}
+
+	return xml.Unmarshal(data, out)
+}
+
+func toCPParcel(p Parcel) cpParcel {
+	parcel := cpParcel{Weight: strconv.FormatFloat(float64(p.WeightGrams)/1000, 'f', 3, 64)}
+	if p.LengthCm > 0 && p.WidthCm > 0 && p.HeightCm > 0 {
+		parcel.Dimensions = &cpDimensions{
+			Length: strconv.FormatFloat(p.LengthCm, 'f', 1, 64),
+			Width:  strconv.FormatFloat(p.WidthCm, 'f', 1, 64),
+			Height: strconv.FormatFloat(p.HeightCm, 'f', 1, 64),
+		}
+	}
+	return parcel
+}
+
+func toCPAddress(a models.PostalAddress) cpAddressDetails {
+	return cpAddressDetails{
+		AddressLine1: a.Street,
+		City:         a.City,
+		ProvState:    a.Province,
+		PostalCode:   cpPostalCode(a.PostalCode),
+	}
+}
+
+// cpPostalCode drops the space Canada Post doesn't accept in postal codes
+func cpPostalCode(code string) string {
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,85 @@
+package shipping
+
+import (
+	"errors"
+	"time"
+	"wearhouse/configs"
+	"wearhouse/internal/models"
+)
+
+// ErrNotFound is returned when a carrier doesn't know a tracking number
+var ErrNotFound = errors.New("tracking number not found")
+
+// Carrier is a shipping company sellers can buy labels from
+type Carrier interface {
+	// Name identifies the carrier on shipments
+	Name() string
+	// Rates quotes every service that can carry the parcel
+	Rates(req RateRequest) ([]Rate, error)
+	// CreateLabel buys a label for the parcel with the given service
+	CreateLabel(req LabelRequest) (*Label, error)
+	// Track returns the latest tracking status of a parcel
+	Track(trackingNumber string) (*TrackingStatus, error)
+}
+
+// Parcel describes the box being shipped
+type Parcel struct {
+	WeightGrams int
+	LengthCm    float64
+	WidthCm     float64
+	HeightCm    float64
+}
+
+// RateRequest is what a carrier needs to price a parcel
+type RateRequest struct {
+	From   models.PostalAddress
+	To     models.PostalAddress
+	Parcel Parcel
+}
+
+// Rate is the price of one of a carrier's services
+type Rate struct {
+	ServiceCode string
+	ServiceName string
+	Price       float64
+	Currency    string
+	TransitDays int
+}
+
+// LabelRequest is what a carrier needs to create a shipping label
+type LabelRequest struct {
+	RateRequest
+	ServiceCode string
+	Reference   string // Shown on the label, usually the order ID
+}
+
+// Label is a purchased shipping label
+type Label struct {
+	TrackingNumber string
+	LabelURL       string
+	Price          float64 // Zero if the carrier doesn't say
+}
+
+type TrackingState string
+
+const (
+	TrackingLabelCreated TrackingState = "label_created"
+	TrackingInTransit    TrackingState = "in_transit"
+	TrackingDelivered    TrackingState = "delivered"
+	TrackingException    TrackingState = "exception" // Held, returned or otherwise stuck
+)
+
+// TrackingStatus is where a parcel is according to its carrier
+type TrackingStatus struct {
+	State       TrackingState
+	Description string
+	DeliveredAt *time.Time
+}
+
+// New returns the carrier configured for this deployment
+func New(config *configs.Config) Carrier {
+	if config.ShippingCarrier == "canadapost" {
+		return NewCanadaPost(config.CanadaPost)
+	}
+	return NewFake()
+}
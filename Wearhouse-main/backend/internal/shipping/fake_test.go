@@ -0,0 +1,62 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeTrackingSurvivesANewFake(t *testing.T) {
+	shipped := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	label, err := (&Fake{Now: func() time.Time { return shipped }}).CreateLabel(LabelRequest{
+		RateRequest: RateRequest{Parcel: Parcel{WeightGrams: 500}},
+		ServiceCode: "FAKE.REG",
+	})
+	if err != nil {
+		t.Fatalf("CreateLabel: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  TrackingState
+	}{
+		{"just shipped", 0, TrackingInTransit},
+		{"nearly there", fakeTransit - time.Second, TrackingInTransit},
+		{"arrived", fakeTransit, TrackingDelivered},
+		{"long arrived", 24 * time.Hour, TrackingDelivered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each poll makes a new carrier, as PollShipments does
+			carrier := &Fake{Now: func() time.Time { return shipped.Add(tt.after) }}
+			status, err := carrier.Track(label.TrackingNumber)
+			if err != nil {
+				t.Fatalf("Track: %v", err)
+			}
+			if status.State != tt.want {
+				t.Errorf("state = %s, want %s", status.State, tt.want)
+			}
+			if tt.want == TrackingDelivered {
+				if status.DeliveredAt == nil || !status.DeliveredAt.Equal(shipped.Add(fakeTransit)) {
+					t.Errorf("delivered at %v, want %v", status.DeliveredAt, shipped.Add(fakeTransit))
+				}
+			}
+		})
+	}
+}
+
+func TestFakeTrackUnknownNumber(t *testing.T) {
+	for _, number := range []string{"", "1Z999AA10123456784", "FAKE-soon-abcdef12"} {
+		if _, err := NewFake().Track(number); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Track(%q) = %v, want ErrNotFound", number, err)
+		}
+	}
+}
+
+func TestFakeCreateLabelUnknownService(t *testing.T) {
+	if _, err := NewFake().CreateLabel(LabelRequest{ServiceCode: "FAKE.NOPE"}); err == nil {
+		t.Error("CreateLabel accepted an unknown service")
+	}
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AddressRequest represents the request to create or update an address
+type AddressRequest struct {
+	Label      string `json:"label" validate:"max=50"`
+	Name       string `json:"name" validate:"required,max=200"`
+	Street     string `json:"street" validate:"required,max=255"`
+	City       string `json:"city" validate:"required,max=100"`
+	Province   string `json:"province" validate:"required,province"`
+	PostalCode string `json:"postal_code" validate:"required,postal_code_ca"`
+	Phone      string `json:"phone" validate:"omitempty,phone_ca"`
+}
+
+// PostalAddressResponse represents the parts of a mailing address
+type PostalAddressResponse struct {
+	Name       string `json:"name"`
+	Street     string `json:"street"`
+	City       string `json:"city"`
+	Province   string `json:"province"`
+	PostalCode string `json:"postal_code"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+// AddressResponse represents an address book entry in the response
+type AddressResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label,omitempty"`
+	PostalAddressResponse
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 )
@@ -14,7 +15,7 @@ type AddToCartRequest struct {
 
 // UpdateCartItemRequest represents the request to update a cart item
 type UpdateCartItemRequest struct {
-	Quantity int `json:"quantity" validate:"required,min=0"`
+	Quantity int `json:"quantity" validate:"min=0"` // 0 removes the item
 }
 
 // CartItemResponse represents a cart item in the response
@@ -23,16 +24,52 @@ type CartItemResponse struct {
 	ProductID uuid.UUID       `json:"product_id"`
 	Product   ProductResponse `json:"product"`
 	Quantity  int             `json:"quantity"`
+	Price     money.Money     `json:"price"`              // Unit price, the agreed amount for offers
+	OfferID   *uuid.UUID      `json:"offer_id,omitempty"` // Set when the price was negotiated
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
 }
 
 // CartResponse represents the cart in the response
 type CartResponse struct {
-	ID        uuid.UUID          `json:"id"`
-	UserID    uuid.UUID          `json:"user_id"`
-	Items     []CartItemResponse `json:"items"`
-	Total     float64            `json:"total"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID        uuid.UUID           `json:"id"`
+	UserID    uuid.UUID           `json:"user_id"`
+	Items     []CartItemResponse  `json:"items"`
+	Subtotal  money.Money         `json:"subtotal"` // The items, before the coupon
+	Coupon    *CartCouponResponse `json:"coupon,omitempty"`
+	Discount  money.Money         `json:"discount"`
+	Total     money.Money         `json:"total"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// ValidateCartRequest represents the request to reconcile the cart
+type ValidateCartRequest struct {
+	Acknowledge bool `json:"acknowledge"` // Apply the reported changes to the cart
+}
+
+// Reasons a cart item no longer matches its listing
+const (
+	CartChangeRemoved         = "removed"
+	CartChangeUnavailable     = "unavailable"
+	CartChangePriceChanged    = "price_changed"
+	CartChangeSellerSuspended = "seller_suspended"
+)
+
+// CartItemChange describes how a cart item differs from its live listing
+type CartItemChange struct {
+	ItemID    uuid.UUID    `json:"item_id"`
+	ProductID uuid.UUID    `json:"product_id"`
+	Title     string       `json:"title"`
+	Reason    string       `json:"reason"`
+	OldPrice  *money.Money `json:"old_price,omitempty"`
+	NewPrice  *money.Money `json:"new_price,omitempty"`
+}
+
+// CartValidationResponse represents the result of reconciling the cart
+type CartValidationResponse struct {
+	Valid        bool             `json:"valid"`
+	Acknowledged bool             `json:"acknowledged"`
+	Changes      []CartItemChange `json:"changes"`
+	Cart         CartResponse     `json:"cart"`
 }
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// CreateCouponRequest represents a new promo code. Empty scopes cover
+// anything.
+type CreateCouponRequest struct {
+	Code           string      `json:"code" validate:"required,min=3,max=50,alphanum"`
+	Description    string      `json:"description" validate:"max=500"`
+	Type           string      `json:"type" validate:"required,oneof=percent fixed"`
+	PercentBPS     int         `json:"percent_bps" validate:"min=0,max=10000"` // For percent coupons
+	Amount         money.Money `json:"amount" validate:"min=0"`                // For fixed coupons
+	MaxDiscount    money.Money `json:"max_discount" validate:"min=0"`          // 0 for no cap
+	MinSpend       money.Money `json:"min_spend" validate:"min=0"`
+	University     string      `json:"university" validate:"max=255"`
+	Category       string      `json:"category" validate:"max=50"`
+	SellerID       *uuid.UUID  `json:"seller_id"`
+	MaxUses        int         `json:"max_uses" validate:"min=0"`                    // 0 for no limit
+	MaxUsesPerUser *int        `json:"max_uses_per_user" validate:"omitempty,min=0"` // Defaults to 1, 0 for no limit
+	StartsAt       *time.Time  `json:"starts_at"`
+	EndsAt         *time.Time  `json:"ends_at"`
+}
+
+// CouponResponse represents a coupon in the response
+type CouponResponse struct {
+	ID             uuid.UUID    `json:"id"`
+	Code           string       `json:"code"`
+	Description    string       `json:"description,omitempty"`
+	Type           string       `json:"type"`
+	PercentBPS     int          `json:"percent_bps,omitempty"`
+	Amount         *money.Money `json:"amount,omitempty"`
+	MaxDiscount    *money.Money `json:"max_discount,omitempty"`
+	MinSpend       money.Money  `json:"min_spend"`
+	University     string       `json:"university,omitempty"`
+	Category       string       `json:"category,omitempty"`
+	SellerID       *uuid.UUID   `json:"seller_id,omitempty"`
+	MaxUses        int          `json:"max_uses"`
+	MaxUsesPerUser int          `json:"max_uses_per_user"`
+	Uses           int64        `json:"uses"`
+	StartsAt       *time.Time   `json:"starts_at,omitempty"`
+	EndsAt         *time.Time   `json:"ends_at,omitempty"`
+	IsActive       bool         `json:"is_active"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// ApplyCouponRequest represents a buyer entering a promo code
+type ApplyCouponRequest struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
+
+// CartCouponResponse represents the coupon applied to the cart. Problem
+// says why it takes nothing off at the moment, e.g. once it has expired.
+type CartCouponResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Problem     string `json:"problem,omitempty"`
+}
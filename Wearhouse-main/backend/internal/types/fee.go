@@ -0,0 +1,63 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// FeeRuleRequest represents one rule of a new fee schedule. Empty conditions
+// match anything.
+type FeeRuleRequest struct {
+	Name        string      `json:"name" validate:"required,max=100"`
+	ListingType string      `json:"listing_type" validate:"omitempty,oneof=SALE TRADE FREE"`
+	Category    string      `json:"category" validate:"max=50"`
+	SellerTier  string      `json:"seller_tier" validate:"omitempty,oneof=standard pro"`
+	MinPrice    money.Money `json:"min_price" validate:"min=0"`
+	MaxPrice    money.Money `json:"max_price" validate:"min=0"` // 0 for no upper bound
+	PercentBPS  int         `json:"percent_bps" validate:"min=0,max=10000"`
+	Fixed       money.Money `json:"fixed" validate:"min=0"`
+	MinFee      money.Money `json:"min_fee" validate:"min=0"`
+	MaxFee      money.Money `json:"max_fee" validate:"min=0"` // 0 for no cap
+}
+
+// CreateFeeScheduleRequest represents a new version of the fee rules, in
+// the order they are tried
+type CreateFeeScheduleRequest struct {
+	EffectiveFrom *time.Time       `json:"effective_from"` // Defaults to now
+	Note          string           `json:"note" validate:"max=500"`
+	Rules         []FeeRuleRequest `json:"rules" validate:"required,max=100,dive"`
+}
+
+// FeeRuleResponse represents a fee rule in the response
+type FeeRuleResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Position    int         `json:"position"`
+	Name        string      `json:"name"`
+	ListingType string      `json:"listing_type,omitempty"`
+	Category    string      `json:"category,omitempty"`
+	SellerTier  string      `json:"seller_tier,omitempty"`
+	MinPrice    money.Money `json:"min_price"`
+	MaxPrice    money.Money `json:"max_price"`
+	PercentBPS  int         `json:"percent_bps"`
+	Fixed       money.Money `json:"fixed"`
+	MinFee      money.Money `json:"min_fee"`
+	MaxFee      money.Money `json:"max_fee"`
+}
+
+// FeeScheduleResponse represents a version of the fee rules in the response
+type FeeScheduleResponse struct {
+	ID            uuid.UUID         `json:"id"`
+	Version       int               `json:"version"`
+	EffectiveFrom time.Time         `json:"effective_from"`
+	Note          string            `json:"note,omitempty"`
+	CreatedByID   *uuid.UUID        `json:"created_by_id,omitempty"`
+	Rules         []FeeRuleResponse `json:"rules"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// SellerTierRequest represents an admin changing a seller's fee tier
+type SellerTierRequest struct {
+	Tier string `json:"tier" validate:"required,oneof=standard pro"`
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MeetupSpotRequest represents the request to create or update a meetup spot
+type MeetupSpotRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description" validate:"max=1000"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// MeetupSpotResponse represents a meetup spot in the response
+type MeetupSpotResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	IsActive    bool      `json:"is_active"`
+}
+
+// ProposeMeetupRequest represents a time slot at a spot proposed for a meetup
+type ProposeMeetupRequest struct {
+	SpotID   uuid.UUID `json:"spot_id" validate:"required"`
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+}
+
+// MeetupProposalResponse represents a proposed meetup in the response
+type MeetupProposalResponse struct {
+	ID           uuid.UUID          `json:"id"`
+	Spot         MeetupSpotResponse `json:"spot"`
+	StartsAt     time.Time          `json:"starts_at"`
+	ProposedByID uuid.UUID          `json:"proposed_by_id"`
+	ProposedBy   string             `json:"proposed_by"`
+	Status       string             `json:"status"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// MeetupResponse represents where and when a meetup order is handed over
+type MeetupResponse struct {
+	Spot      *MeetupSpotResponse      `json:"spot,omitempty"`
+	StartsAt  *time.Time               `json:"starts_at,omitempty"`
+	Proposals []MeetupProposalResponse `json:"proposals"`
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// CreateOfferRequest represents a buyer's offer on a listing
+type CreateOfferRequest struct {
+	ProductID uuid.UUID `json:"product_id" validate:"required"`
+	Amount    float64   `json:"amount" validate:"required,gt=0"`
+	Message   string    `json:"message" validate:"max=500"`
+}
+
+// CounterOfferRequest represents a counter-proposal on an open offer
+type CounterOfferRequest struct {
+	Amount  float64 `json:"amount" validate:"required,gt=0"`
+	Message string  `json:"message" validate:"max=500"`
+}
+
+// OfferResponse represents an offer in the response
+type OfferResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	ProductID uuid.UUID       `json:"product_id"`
+	Product   ProductResponse `json:"product"`
+	BuyerID   uuid.UUID       `json:"buyer_id"`
+	SellerID  uuid.UUID       `json:"seller_id"`
+	Amount    money.Money     `json:"amount"`
+	Message   string          `json:"message,omitempty"`
+	Status    string          `json:"status"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
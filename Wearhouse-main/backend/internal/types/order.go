@@ -2,43 +2,144 @@ package types
 
 import (
 	"time"
+	"wearhouse/internal/money"
 
 	"github.com/google/uuid"
 )
 
 // CreateOrderRequest represents the request to create a new order
 type CreateOrderRequest struct {
-	ShippingAddr  string `json:"shipping_addr" validate:"required"`
-	BillingAddr   string `json:"billing_addr" validate:"required"`
-	PaymentMethod string `json:"payment_method" validate:"required,oneof=credit_card paypal"`
+	FulfilmentMethod  string     `json:"fulfilment_method"`   // ship (default) or meetup
+	ShippingAddressID *uuid.UUID `json:"shipping_address_id"` // Not needed for meetups
+	BillingAddressID  *uuid.UUID `json:"billing_address_id"`  // Defaults to the shipping address
+	PaymentMethod     string     `json:"payment_method" validate:"required,oneof=credit_card paypal cash_on_meetup"`
+	UseCredit         bool       `json:"use_credit"` // Pay with store credit first, the rest with the payment method
 }
 
 // OrderItemResponse represents a single item in an order response
 type OrderItemResponse struct {
-	ID        uuid.UUID       `json:"id"`
-	ProductID uuid.UUID       `json:"product_id"`
-	Product   ProductResponse `json:"product"`
-	Quantity  int             `json:"quantity"`
-	Price     float64         `json:"price"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	ID         uuid.UUID   `json:"id"`
+	ProductID  uuid.UUID   `json:"product_id"`
+	Title      string      `json:"title"`
+	Brand      string      `json:"brand"`
+	Size       string      `json:"size"`
+	Condition  string      `json:"condition"`
+	ImageURL   string      `json:"image_url,omitempty"`
+	SellerID   uuid.UUID   `json:"seller_id"`
+	SellerName string      `json:"seller_name"`
+	Quantity   int         `json:"quantity"`
+	Price      money.Money `json:"price"`
+	OfferID    *uuid.UUID  `json:"offer_id,omitempty"`
+	Fee        money.Money `json:"fee"`
+	Discount   money.Money `json:"discount"` // Taken off by the coupon
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
 }
 
 // OrderResponse represents the response for order operations
 type OrderResponse struct {
-	ID            uuid.UUID           `json:"id"`
-	UserID        uuid.UUID           `json:"user_id"`
-	Items         []OrderItemResponse `json:"items"`
-	Status        string              `json:"status"`
-	Total         float64             `json:"total"`
-	ShippingAddr  string              `json:"shipping_addr"`
-	BillingAddr   string              `json:"billing_addr"`
-	PaymentMethod string              `json:"payment_method"`
-	CreatedAt     time.Time           `json:"created_at"`
-	UpdatedAt     time.Time           `json:"updated_at"`
+	ID               uuid.UUID                    `json:"id"`
+	CheckoutID       uuid.UUID                    `json:"checkout_id"`
+	UserID           uuid.UUID                    `json:"user_id"`
+	SellerID         uuid.UUID                    `json:"seller_id"`
+	Items            []OrderItemResponse          `json:"items"`
+	Status           string                       `json:"status"`
+	FulfilmentMethod string                       `json:"fulfilment_method"`
+	Subtotal         money.Money                  `json:"subtotal"` // The items, before fees
+	CouponCode       string                       `json:"coupon_code,omitempty"`
+	Discount         money.Money                  `json:"discount"` // Taken off the subtotal by the coupon
+	Fees             []OrderFeeResponse           `json:"fees,omitempty"`
+	Fee              money.Money                  `json:"fee"` // All the fees
+	Taxes            []OrderTaxResponse           `json:"taxes,omitempty"`
+	Tax              money.Money                  `json:"tax"`    // All the taxes
+	Total            money.Money                  `json:"total"`  // What the buyer pays
+	Credit           money.Money                  `json:"credit"` // Of the total, paid with store credit
+	RefundAsCredit   bool                         `json:"refund_as_credit,omitempty"`
+	ShippingAddress  *PostalAddressResponse       `json:"shipping_address,omitempty"`
+	BillingAddress   *PostalAddressResponse       `json:"billing_address,omitempty"`
+	ShippingAddr     string                       `json:"shipping_addr"`
+	BillingAddr      string                       `json:"billing_addr"`
+	PaymentMethod    string                       `json:"payment_method"`
+	Meetup           *MeetupResponse              `json:"meetup,omitempty"`
+	Shipment         *ShipmentResponse            `json:"shipment,omitempty"`
+	HandedOverAt     *time.Time                   `json:"handed_over_at,omitempty"`
+	StatusHistory    []OrderStatusHistoryResponse `json:"status_history"`
+	Refunds          []RefundResponse             `json:"refunds,omitempty"`
+	CreatedAt        time.Time                    `json:"created_at"`
+	UpdatedAt        time.Time                    `json:"updated_at"`
+}
+
+// OrderFeeResponse represents the platform fee charged on one order item
+type OrderFeeResponse struct {
+	OrderItemID uuid.UUID   `json:"order_item_id"`
+	Name        string      `json:"name"`
+	Amount      money.Money `json:"amount"`
+}
+
+// CheckoutResponse represents a checkout and its per-seller orders
+type CheckoutResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Orders        []OrderResponse `json:"orders"`
+	Status        string          `json:"status"`
+	Total         money.Money     `json:"total"`
+	Credit        money.Money     `json:"credit"`     // Paid with store credit
+	AmountDue     money.Money     `json:"amount_due"` // Left to pay with the payment method
+	PaymentMethod string          `json:"payment_method"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// OrderStatusHistoryResponse represents one status change of an order
+type OrderStatusHistoryResponse struct {
+	FromStatus string     `json:"from_status,omitempty"`
+	ToStatus   string     `json:"to_status"`
+	ActorID    *uuid.UUID `json:"actor_id,omitempty"`
+	ActorRole  string     `json:"actor_role"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 // UpdateOrderStatusRequest represents the request to update an order's status
 type UpdateOrderStatusRequest struct {
-	Status string `json:"status" validate:"required,oneof=pending paid shipped delivered cancelled"`
+	Status string `json:"status" validate:"required,oneof=pending paid shipped ready_for_pickup delivered cancelled"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// CancelOrderRequest represents the request to cancel an order
+type CancelOrderRequest struct {
+	Reason   string `json:"reason" validate:"max=500"`
+	RefundTo string `json:"refund_to" validate:"omitempty,oneof=original credit"` // Buyers only, defaults to the original payment method
+}
+
+// SellerActionRequest represents an optional note on a seller action
+type SellerActionRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// SalesSummary represents a seller's totals across their sales
+type SalesSummary struct {
+	ItemsSold      int64       `json:"items_sold"`
+	GrossRevenue   money.Money `json:"gross_revenue"`
+	PendingPayouts money.Money `json:"pending_payouts"` // Paid orders not yet delivered
+}
+
+// SalesListResponse represents the seller's sales dashboard
+type SalesListResponse struct {
+	Orders  []OrderResponse `json:"orders"`
+	Summary SalesSummary    `json:"summary"`
+}
+
+// HandoffCodeResponse represents the code a buyer shows the seller at the
+// handoff, as text and as a payload for a QR code
+type HandoffCodeResponse struct {
+	OrderID   uuid.UUID `json:"order_id"`
+	Code      string    `json:"code"`
+	QRPayload string    `json:"qr_payload"`
+}
+
+// HandoffRequest represents the code, or scanned QR payload, a seller enters
+// to confirm a handoff
+type HandoffRequest struct {
+	Code string `json:"code" validate:"required,max=255"`
 }
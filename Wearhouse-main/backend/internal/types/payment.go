@@ -1,15 +1,44 @@
 package types
 
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// CreatePaymentIntentRequest pays for a checkout. Passing one of its orders
+// instead pays for the whole checkout that order belongs to. The amount is
+// always worked out on the server.
 type CreatePaymentIntentRequest struct {
-	OrderID     string  `json:"order_id" validate:"required,uuid"`
-	Amount      float64 `json:"amount" validate:"required,gt=0"`
-	Currency    string  `json:"currency" validate:"required,len=3"`
-	Description string  `json:"description"`
+	CheckoutID  string `json:"checkout_id" validate:"required_without=OrderID,omitempty,uuid"`
+	OrderID     string `json:"order_id" validate:"required_without=CheckoutID,omitempty,uuid"`
+	Description string `json:"description" validate:"max=500"`
+	ReturnURL   string `json:"return_url" validate:"omitempty,url"` // Where PayPal sends the buyer back to
+	CancelURL   string `json:"cancel_url" validate:"omitempty,url"`
 }
 
 type CreatePaymentIntentResponse struct {
-	ClientSecret string `json:"client_secret"`
-	PaymentID    string `json:"payment_id"`
+	ClientSecret  string      `json:"client_secret"`
+	PaymentID     string      `json:"payment_id"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	NextActionURL string      `json:"next_action_url,omitempty"` // Where to send the buyer to approve the payment, for PayPal
+}
+
+// ConfirmPaymentRequest charges a payment method, a token from the
+// provider's client library, against a payment. PayPal payments are
+// captured once approved and need no payment method.
+type ConfirmPaymentRequest struct {
+	PaymentMethod string `json:"payment_method" validate:"max=255"`
+	ReturnURL     string `json:"return_url" validate:"omitempty,url"`
+}
+
+type ConfirmPaymentResponse struct {
+	PaymentID     string `json:"payment_id"`
+	Status        string `json:"status"`                    // The provider's status for the payment
+	NextActionURL string `json:"next_action_url,omitempty"` // Where to send the buyer to authenticate
+	Error         string `json:"error,omitempty"`
 }
 
 type PaymentWebhookRequest struct {
@@ -30,4 +59,34 @@ const (
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
+	// PaymentStatusPartiallyRefunded is used when only some of a checkout's
+	// orders were cancelled
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	// PaymentStatusNeedsReview is used for payments that went through once
+	// their checkout could no longer be paid for. They are refunded in full.
+	PaymentStatusNeedsReview PaymentStatus = "needs_review"
 )
+
+// RefundResponse represents a refund in the response
+type RefundResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	PaymentID   uuid.UUID   `json:"payment_id"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Status      string      `json:"status"`
+	Reason      string      `json:"reason,omitempty"`
+	InitiatedBy string      `json:"initiated_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type WebhookEventResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Provider    string     `json:"provider"`
+	EventID     string     `json:"event_id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
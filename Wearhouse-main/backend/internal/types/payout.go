@@ -0,0 +1,18 @@
+package types
+
+import "wearhouse/internal/money"
+
+// BalanceResponse represents what a seller has earned, by where the money is
+type BalanceResponse struct {
+	Currency               string      `json:"currency"`
+	Pending                money.Money `json:"pending"`   // Held until orders are delivered
+	Available              money.Money `json:"available"` // Paid out with the next payout
+	PaidOut                money.Money `json:"paid_out"`
+	PayoutAccountConnected bool        `json:"payout_account_connected"`
+}
+
+// PayoutAccountResponse represents where a seller finishes setting up the
+// account they are paid into
+type PayoutAccountResponse struct {
+	OnboardingURL string `json:"onboarding_url"`
+}
@@ -1,6 +1,9 @@
 package types
 
-import "mime/multipart"
+import (
+	"mime/multipart"
+	"wearhouse/internal/money"
+)
 
 type CreateProductRequest struct {
 	Title       string                  `form:"title" json:"title" validate:"required,min=3,max=255"`
@@ -9,7 +12,9 @@ type CreateProductRequest struct {
 	Size        string                  `form:"size" json:"size" validate:"required"`
 	Brand       string                  `form:"brand" json:"brand" validate:"required"`
 	Condition   string                  `form:"condition" json:"condition" validate:"required,oneof=new like_new good fair poor"`
+	ListingType string                  `form:"listing_type" json:"listing_type" validate:"omitempty,oneof=SALE TRADE FREE"`
 	Price       float64                 `form:"price" json:"price" validate:"required,gt=0"`
+	AcceptsCash bool                    `form:"accepts_cash" json:"accepts_cash"`
 	Images      []*multipart.FileHeader `form:"images" json:"images" validate:"omitempty,max=5"`
 }
 
@@ -21,23 +26,26 @@ type UpdateProductRequest struct {
 	Brand       *string                 `form:"brand"`
 	Condition   *string                 `form:"condition" validate:"omitempty,oneof=new like_new good fair poor"`
 	Price       *float64                `form:"price" validate:"omitempty,gt=0"`
+	AcceptsCash *bool                   `form:"accepts_cash"`
 	Images      []*multipart.FileHeader `form:"images" validate:"omitempty,max=5"`
 }
 
 type ProductResponse struct {
-	ID          string   `json:"id"`
-	UserID      string   `json:"user_id"`
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Category    string   `json:"category"`
-	Size        string   `json:"size"`
-	Brand       string   `json:"brand"`
-	Condition   string   `json:"condition"`
-	Price       float64  `json:"price"`
-	IsAvailable bool     `json:"is_available"`
-	Images      []string `json:"images"`
-	CreatedAt   string   `json:"created_at"`
-	UpdatedAt   string   `json:"updated_at"`
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	Size        string      `json:"size"`
+	Brand       string      `json:"brand"`
+	Condition   string      `json:"condition"`
+	ListingType string      `json:"listing_type"`
+	Price       money.Money `json:"price"`
+	IsAvailable bool        `json:"is_available"`
+	AcceptsCash bool        `json:"accepts_cash"`
+	Images      []string    `json:"images"`
+	CreatedAt   string      `json:"created_at"`
+	UpdatedAt   string      `json:"updated_at"`
 }
 
 type ProductListResponse struct {
@@ -47,11 +55,16 @@ type ProductListResponse struct {
 	PerPage  int               `json:"per_page"`
 }
 
+// ProductFilters holds the query parameters accepted by the product listing.
+// Category, size, brand, condition and listing type accept several values,
+// either comma-separated (brand=nike,adidas) or repeated (size=M&size=L).
+// A value prefixed with "-" excludes matches instead (brand=-shein).
 type ProductFilters struct {
-	Category    string   `query:"category"`
-	Size        string   `query:"size"`
-	Brand       string   `query:"brand"`
-	Condition   string   `query:"condition"`
+	Category    []string `query:"category"`
+	Size        []string `query:"size"`
+	Brand       []string `query:"brand"`
+	Condition   []string `query:"condition"`
+	ListingType []string `query:"listing_type"`
 	MinPrice    *float64 `query:"min_price"`
 	MaxPrice    *float64 `query:"max_price"`
 	Search      string   `query:"search"`
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// ParcelRequest describes the box a seller is shipping
+type ParcelRequest struct {
+	WeightGrams int     `json:"weight_grams" validate:"required,gt=0,max=30000"`
+	LengthCm    float64 `json:"length_cm" validate:"omitempty,gt=0,max=200"`
+	WidthCm     float64 `json:"width_cm" validate:"omitempty,gt=0,max=200"`
+	HeightCm    float64 `json:"height_cm" validate:"omitempty,gt=0,max=200"`
+}
+
+// ShippingRatesRequest represents a seller asking what shipping an order costs
+type ShippingRatesRequest struct {
+	FromAddressID uuid.UUID     `json:"from_address_id" validate:"required"`
+	Parcel        ParcelRequest `json:"parcel"`
+}
+
+// ShippingRateResponse represents the price of one carrier service
+type ShippingRateResponse struct {
+	Carrier     string      `json:"carrier"`
+	ServiceCode string      `json:"service_code"`
+	ServiceName string      `json:"service_name"`
+	Price       money.Money `json:"price"`
+	Currency    string      `json:"currency"`
+	TransitDays int         `json:"transit_days,omitempty"`
+}
+
+// CreateShipmentRequest represents a seller shipping an order, either by
+// buying a label for one of the quoted services or by entering the tracking
+// number of a label bought elsewhere
+type CreateShipmentRequest struct {
+	FromAddressID  *uuid.UUID     `json:"from_address_id"`
+	Parcel         *ParcelRequest `json:"parcel"`
+	ServiceCode    string         `json:"service_code" validate:"max=50"`
+	Carrier        string         `json:"carrier" validate:"max=50"`
+	TrackingNumber string         `json:"tracking_number" validate:"max=100"`
+}
+
+// ShipmentResponse represents the parcel a shipped order travels in
+type ShipmentResponse struct {
+	ID             uuid.UUID    `json:"id"`
+	Carrier        string       `json:"carrier"`
+	ServiceCode    string       `json:"service_code,omitempty"`
+	TrackingNumber string       `json:"tracking_number"`
+	LabelURL       string       `json:"label_url,omitempty"`
+	Cost           *money.Money `json:"cost,omitempty"`
+	Status         string       `json:"status"`
+	StatusDetail   string       `json:"status_detail,omitempty"`
+	DeliveredAt    *time.Time   `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// CreateTaxRateRequest represents a new rate for a tax in a province. It
+// replaces the province's current rate for the same tax from EffectiveFrom.
+type CreateTaxRateRequest struct {
+	Province      string     `json:"province" validate:"required,province"`
+	Name          string     `json:"name" validate:"required,max=10"` // e.g. GST, HST, PST or QST
+	RatePPM       int64      `json:"rate_ppm" validate:"min=0,max=1000000"`
+	AppliesToFees *bool      `json:"applies_to_fees"` // Defaults to true
+	EffectiveFrom *time.Time `json:"effective_from"`  // Defaults to now
+}
+
+// TaxRateResponse represents a tax rate in the response
+type TaxRateResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Province      string    `json:"province"`
+	Name          string    `json:"name"`
+	RatePPM       int64     `json:"rate_ppm"`
+	RatePercent   string    `json:"rate_percent"`
+	AppliesToFees bool      `json:"applies_to_fees"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// OrderTaxResponse represents a tax charged on an order item or its fee
+type OrderTaxResponse struct {
+	OrderItemID uuid.UUID   `json:"order_item_id"`
+	Base        string      `json:"base"` // item or fee
+	Name        string      `json:"name"`
+	Province    string      `json:"province"`
+	RatePercent string      `json:"rate_percent"`
+	Taxable     money.Money `json:"taxable"`
+	Amount      money.Money `json:"amount"`
+}
+
+// ReceiptLineResponse represents an item on a receipt
+type ReceiptLineResponse struct {
+	Title    string      `json:"title"`
+	Quantity int         `json:"quantity"`
+	Price    money.Money `json:"price"`
+	Fee      money.Money `json:"fee"`
+	Discount money.Money `json:"discount"`
+}
+
+// ReceiptTaxResponse represents one tax on a receipt, across every line
+type ReceiptTaxResponse struct {
+	Name        string      `json:"name"`
+	Province    string      `json:"province"`
+	RatePercent string      `json:"rate_percent"`
+	Amount      money.Money `json:"amount"`
+}
+
+// ReceiptResponse represents the receipt of a paid order
+type ReceiptResponse struct {
+	OrderID       uuid.UUID             `json:"order_id"`
+	CheckoutID    uuid.UUID             `json:"checkout_id"`
+	SellerName    string                `json:"seller_name"`
+	PaymentMethod string                `json:"payment_method"`
+	Currency      string                `json:"currency"`
+	Lines         []ReceiptLineResponse `json:"lines"`
+	Subtotal      money.Money           `json:"subtotal"`
+	Discount      money.Money           `json:"discount"`
+	Fee           money.Money           `json:"fee"`
+	Taxes         []ReceiptTaxResponse  `json:"taxes"`
+	Tax           money.Money           `json:"tax"`
+	Total         money.Money           `json:"total"`
+	OrderedAt     time.Time             `json:"ordered_at"`
+}
@@ -0,0 +1,6 @@
+package types
+
+// UserSuspensionRequest represents an admin suspending or reinstating a user
+type UserSuspensionRequest struct {
+	Suspended *bool `json:"suspended" validate:"required"`
+}
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+	"wearhouse/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// WalletTransactionResponse represents money moving in or out of a wallet
+type WalletTransactionResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Amount      money.Money `json:"amount"` // Negative when credit was spent
+	Description string      `json:"description"`
+	OrderID     *uuid.UUID  `json:"order_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// WalletResponse represents a user's store credit, newest transactions first
+type WalletResponse struct {
+	Currency     string                      `json:"currency"`
+	Balance      money.Money                 `json:"balance"`
+	Held         money.Money                 `json:"held"`      // Set aside for checkouts waiting for payment
+	Available    money.Money                 `json:"available"` // What the next checkout can use
+	Transactions []WalletTransactionResponse `json:"transactions"`
+}
+
+// WalletDepositRequest represents a seller keeping their earnings as store
+// credit instead of having them paid out
+type WalletDepositRequest struct {
+	Amount money.Money `json:"amount" validate:"min=0"` // 0 for everything available
+}
@@ -4,8 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"math/big"
 	"net/smtp"
 	"regexp"
+	"time"
 	"wearhouse/configs"
 )
 
@@ -26,11 +28,18 @@ func GenerateVerificationToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// GenerateHandoffCode generates the six digit code a buyer shows the seller
+// when an item is handed over
+func GenerateHandoffCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
 // SendVerificationEmail sends a verification email to the user
 func SendVerificationEmail(to, token string, config *configs.Config) error {
-	// Email server configuration
-	auth := smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, config.SMTP.Host)
-
 	verificationLink := fmt.Sprintf("%s/verify-email?token=%s", config.AppURL, token)
 
 	subject := "Verify your WearHouse account"
@@ -47,6 +56,38 @@ Best regards,
 The WearHouse Team
 `, verificationLink)
 
+	return sendEmail(to, subject, body, config)
+}
+
+// SendMeetupReminderEmail reminds a buyer or seller of an upcoming meetup
+func SendMeetupReminderEmail(to, itemTitle, spot string, at time.Time, config *configs.Config) error {
+	// Campus time, whatever zone the server runs in
+	if loc, err := time.LoadLocation("America/Toronto"); err == nil {
+		at = at.In(loc)
+	}
+
+	subject := "Your WearHouse meetup is coming up"
+	body := fmt.Sprintf(`
+Hello!
+
+This is a reminder that you're meeting up to hand over %s:
+
+Where: %s
+When:  %s
+
+Please be on time, and remember to confirm the handoff in the app.
+
+Best regards,
+The WearHouse Team
+`, itemTitle, spot, at.Format("Monday, January 2 at 3:04 PM MST"))
+
+	return sendEmail(to, subject, body, config)
+}
+
+// sendEmail sends a plain text email through the configured SMTP server
+func sendEmail(to, subject, body string, config *configs.Config) error {
+	auth := smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, config.SMTP.Host)
+
 	msg := fmt.Sprintf("To: %s\r\n"+
 		"Subject: %s\r\n"+
 		"Content-Type: text/plain; charset=UTF-8\r\n"+
@@ -14,8 +14,7 @@ order_data = {
             "quantity": 1
         }
     ],
-    "shipping_addr": "123 Test St, Test City, TS 12345",
-    "billing_addr": "123 Test St, Test City, TS 12345",
+    "shipping_address_id": "3f0c8d1e-5b7a-4c2e-9d6f-1a2b3c4d5e6f",  # Address ID from the address book
     "payment_method": "credit_card"
 }
 
@@ -1,6 +1,7 @@
 import requests
 import json
 import sys
+import uuid
 
 # Configuration
 BASE_URL = "http://localhost:8080/api"
@@ -47,8 +48,8 @@ def login():
     return TOKEN
 
 # Helper function to make authenticated requests
-def auth_request(method, endpoint, json=None):
-    headers = {"Authorization": f"Bearer {TOKEN}"}
+def auth_request(method, endpoint, json=None, headers=None):
+    headers = {"Authorization": f"Bearer {TOKEN}", **(headers or {})}
     return requests.request(method, f"{BASE_URL}{endpoint}", headers=headers, json=json)
 
 def main():
@@ -77,19 +78,45 @@ def main():
     print("Adding product to cart...")
     cart_response = auth_request("POST", "/cart/items", json={
         "product_id": product_id,
-        "quantity": 2
+        "quantity": 1
     })
     print_response(cart_response)
 
+    # Add a shipping address
+    print("Creating address...")
+    address_response = auth_request("POST", "/addresses", json={
+        "label": "Residence",
+        "name": "Test User",
+        "street": "1125 Colonel By Dr",
+        "city": "Ottawa",
+        "province": "ON",
+        "postal_code": "K1S 5B6",
+        "phone": "613-555-0100"
+    })
+    print_response(address_response)
+    if address_response.status_code != 201:
+        print("Address creation failed")
+        sys.exit(1)
+    address_id = address_response.json()["id"]
+
     # Create order
     print("Creating order...")
-    order_response = auth_request("POST", "/orders", json={
-        "shipping_addr": "123 Test St, Test City, TS 12345",
-        "billing_addr": "123 Test St, Test City, TS 12345",
+    order_request = {
+        "shipping_address_id": address_id,
         "payment_method": "credit_card"
-    })
+    }
+    idempotency = {"Idempotency-Key": str(uuid.uuid4())}
+    order_response = auth_request("POST", "/orders", json=order_request, headers=idempotency)
     print_response(order_response)
-    order_id = order_response.json()["id"]
+    order_id = order_response.json()["orders"][0]["id"]
+
+    # Retrying with the same key replays the order instead of placing another
+    print("Retrying order creation...")
+    retry_response = auth_request("POST", "/orders", json=order_request, headers=idempotency)
+    print_response(retry_response)
+    if retry_response.json()["orders"][0]["id"] != order_id:
+        print("Expected the retry to return the same order")
+        sys.exit(1)
 
     # Get all orders
     print("Getting all orders...")
@@ -101,12 +128,23 @@ def main():
     order_response = auth_request("GET", f"/orders/{order_id}")
     print_response(order_response)
 
-    # Update order status
-    print("Updating order status...")
+    # Buyers can't mark their own order as paid
+    print("Trying to mark order as paid...")
     status_response = auth_request("PUT", f"/orders/{order_id}/status", json={
         "status": "paid"
     })
     print_response(status_response)
+    if status_response.status_code != 403:
+        print("Expected buyer to be forbidden from marking order as paid")
+        sys.exit(1)
+
+    # Cancel the unpaid order
+    print("Cancelling order...")
+    status_response = auth_request("PUT", f"/orders/{order_id}/status", json={
+        "status": "cancelled",
+        "reason": "Changed my mind"
+    })
+    print_response(status_response)
 
 if __name__ == "__main__":
     main() 
\ No newline at end of file
@@ -10,8 +10,6 @@ AUTH_TOKEN = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ1c2VyX2lkIjoiMTk1NTVhMTItY
 # Test data for payment intent
 payment_data = {
     "order_id": "7db1b68b-d970-4ea1-9574-117446c8356e",  # Order ID from previous step
-    "amount": 1000,  # $10.00 in cents
-    "currency": "usd",
     "description": "Test payment for order"
 }
 